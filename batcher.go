@@ -2,8 +2,13 @@ package slackio
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Batcher is a type for functions that emit the output of an io.Reader in
@@ -45,6 +50,28 @@ func LineBatcher(r io.Reader) (<-chan string, <-chan error) {
 // implementation to make NewIntervalBatcher's interface easier to use.
 var timeAfter = time.After
 
+// timeNow allows for mocking of time.Now in tests.
+var timeNow = time.Now
+
+// Clock is a source of time for Batchers that wait for intervals to elapse,
+// which allows for control over their timing (e.g. in tests).
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// defaultClock is the Clock used by Batchers that are not given one. It reads
+// the time through timeNow and timeAfter.
+type defaultClock struct{}
+
+func (defaultClock) Now() time.Time {
+	return timeNow()
+}
+
+func (defaultClock) After(d time.Duration) <-chan time.Time {
+	return timeAfter(d)
+}
+
 // NewIntervalBatcher returns a Batcher that collects the output of an upstream
 // Batcher over a defined interval. When the upstream Batcher first emits an
 // output batch, it is collected into a buffer and a timer is started lasting
@@ -58,6 +85,12 @@ var timeAfter = time.After
 // The batching interval can be adjusted based on the nature of the expected
 // output, though it is recommended that it be kept short.
 func NewIntervalBatcher(b Batcher, d time.Duration, delim string) Batcher {
+	return NewIntervalBatcherWithClock(b, d, delim, defaultClock{})
+}
+
+// NewIntervalBatcherWithClock returns a Batcher exactly as NewIntervalBatcher
+// does, but that uses the given Clock to time its interval.
+func NewIntervalBatcherWithClock(b Batcher, d time.Duration, delim string, clock Clock) Batcher {
 	return func(r io.Reader) (<-chan string, <-chan error) {
 		inCh, inErrCh := b(r)
 		outCh, outErrCh := make(chan string), make(chan error, 1)
@@ -94,7 +127,7 @@ func NewIntervalBatcher(b Batcher, d time.Duration, delim string) Batcher {
 					}
 
 					if timer == nil {
-						timer = timeAfter(d)
+						timer = clock.After(d)
 					}
 
 				case <-timer:
@@ -107,3 +140,392 @@ func NewIntervalBatcher(b Batcher, d time.Duration, delim string) Batcher {
 		return outCh, outErrCh
 	}
 }
+
+// NewGroupByBatcher returns a Batcher that collects the output of an upstream
+// Batcher into separate groups, based on the key that the given function
+// returns for each batch. Each group behaves much like the buffer of
+// NewIntervalBatcher: a timer lasting for the given duration starts when the
+// group receives its first batch, later batches in the group are appended to
+// it with the provided delimiter, and the group is flushed to the output
+// channel as a single batch when its timer expires. When the upstream Batcher
+// terminates, all remaining groups are flushed in the order that they were
+// started.
+//
+// For example, a key function that returns the severity prefix of a log line
+// (e.g. "ERROR" or "INFO") will cause each severity to be sent as its own
+// message.
+func NewGroupByBatcher(b Batcher, key func(string) string, d time.Duration, delim string) Batcher {
+	return NewGroupByBatcherWithClock(b, key, d, delim, defaultClock{})
+}
+
+// NewGroupByBatcherWithClock returns a Batcher exactly as NewGroupByBatcher
+// does, but that uses the given Clock to time the interval of each group.
+func NewGroupByBatcherWithClock(b Batcher, key func(string) string, d time.Duration, delim string, clock Clock) Batcher {
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		groups := make(map[string]string)
+		var order []string
+
+		expired := make(chan string)
+		done := make(chan struct{})
+
+		flushGroup := func(k string) {
+			if groups[k] != "" {
+				outCh <- groups[k]
+			}
+
+			delete(groups, k)
+			for i := range order {
+				if order[i] == k {
+					order = append(order[:i], order[i+1:]...)
+					break
+				}
+			}
+		}
+
+		go func() {
+			for {
+				select {
+				case s, ok := <-inCh:
+					if !ok {
+						close(done)
+						for len(order) > 0 {
+							flushGroup(order[0])
+						}
+						close(outCh)
+
+						outErrCh <- <-inErrCh
+						close(outErrCh)
+
+						return
+					}
+
+					k := key(s)
+					if output, ok := groups[k]; ok {
+						groups[k] = output + delim + s
+						continue
+					}
+
+					groups[k] = s
+					order = append(order, k)
+
+					// Each group's timer waits in its own goroutine, which stops early if
+					// the upstream Batcher terminates first.
+					timer := clock.After(d)
+					go func() {
+						select {
+						case <-timer:
+							select {
+							case expired <- k:
+							case <-done:
+							}
+						case <-done:
+						}
+					}()
+
+				case k := <-expired:
+					flushGroup(k)
+				}
+			}
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// NewByteSizeBatcher returns a Batcher that splits each batch emitted by an
+// upstream Batcher into pieces no longer than maxBytes bytes, as encoded in
+// UTF-8. Splits occur at line breaks where possible, and never occur in the
+// middle of a multi-byte character. Line breaks at the boundaries between
+// pieces are removed, so that a batch joined with a delimiter like "\n\n" (see
+// NewIntervalBatcher) does not produce pieces that begin or end with blank
+// lines. Empty batches, and oversized batches made up only of line breaks, are
+// dropped. As a special case, a single character longer than maxBytes is
+// emitted whole rather than being corrupted. maxBytes must be positive, or
+// NewByteSizeBatcher will panic.
+func NewByteSizeBatcher(b Batcher, maxBytes int) Batcher {
+	if maxBytes <= 0 {
+		panic(errors.New("slackio: NewByteSizeBatcher requires a positive maxBytes"))
+	}
+
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			for s := range inCh {
+				for _, piece := range splitBytes(s, maxBytes) {
+					outCh <- piece
+				}
+			}
+			close(outCh)
+
+			outErrCh <- <-inErrCh
+			close(outErrCh)
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// splitBytes splits s into pieces of at most maxBytes bytes, preferring to
+// split at line breaks and without breaking up any multi-byte characters. It
+// never returns empty pieces, so an empty s produces no pieces at all.
+func splitBytes(s string, maxBytes int) []string {
+	var pieces []string
+
+	for len(s) > maxBytes {
+		i := strings.LastIndexByte(s[:maxBytes+1], '\n')
+		if i <= 0 {
+			i = maxBytes
+			for i > 0 && !utf8.RuneStart(s[i]) {
+				i--
+			}
+		}
+
+		if i == 0 {
+			_, i = utf8.DecodeRuneInString(s)
+		}
+
+		if piece := strings.TrimRight(s[:i], "\n"); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		s = strings.TrimLeft(s[i:], "\n")
+	}
+
+	if s != "" {
+		pieces = append(pieces, s)
+	}
+
+	return pieces
+}
+
+// newWordWrapBatcher returns a Batcher that implements WriterOptionWordWrap for
+// the batches emitted by an upstream Batcher.
+func newWordWrapBatcher(b Batcher, maxBytes, width int) Batcher {
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			for s := range inCh {
+				if len(s) <= maxBytes {
+					outCh <- s
+					continue
+				}
+
+				for _, piece := range splitBytes(wrapWords(s, width), maxBytes) {
+					outCh <- piece
+				}
+			}
+			close(outCh)
+
+			outErrCh <- <-inErrCh
+			close(outErrCh)
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// wrapWords wraps each line of s that is longer than width characters at word
+// boundaries, so that no line is longer than width characters unless it
+// consists of a single longer word. A wrapped line keeps its leading
+// whitespace, which is repeated at the start of each line that it wraps onto,
+// as well as each run of whitespace between words that it is not wrapped at.
+// Lines that already fit are left exactly as they are.
+func wrapWords(s string, width int) string {
+	var out strings.Builder
+
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+
+		if utf8.RuneCountInString(line) <= width {
+			out.WriteString(line)
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		indentLen := utf8.RuneCountInString(indent)
+		out.WriteString(indent)
+
+		// Each word is preceded by the run of whitespace that separates it from
+		// the previous one, which is dropped only where the line is wrapped.
+		lineLen := indentLen
+		rest, sep := line[len(indent):], ""
+		for first := true; rest != ""; first = false {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			next := strings.TrimLeft(rest[end:], " \t")
+
+			wordLen, sepLen := utf8.RuneCountInString(word), utf8.RuneCountInString(sep)
+			switch {
+			case first:
+			case lineLen+sepLen+wordLen > width:
+				out.WriteByte('\n')
+				out.WriteString(indent)
+				lineLen = indentLen
+			default:
+				out.WriteString(sep)
+				lineLen += sepLen
+			}
+
+			out.WriteString(word)
+			lineLen += wordLen
+
+			sep, rest = rest[end:len(rest)-len(next)], next
+		}
+
+		if sepLen := utf8.RuneCountInString(sep); lineLen+sepLen <= width {
+			out.WriteString(sep)
+		}
+	}
+
+	return out.String()
+}
+
+// codeBlockMinBytes is the smallest maxBytes that NewCodeBlockBatcher accepts,
+// which leaves room for a reasonable amount of content alongside the fences and
+// part numbers.
+const codeBlockMinBytes = 64
+
+// NewCodeBlockBatcher returns a Batcher that formats each batch emitted by an
+// upstream Batcher as a code block, so that Slack displays it in a fixed-width
+// font without formatting. Batches that would result in a message longer than
+// maxBytes bytes are first split as by NewByteSizeBatcher, and each resulting
+// piece is fenced separately and preceded by a line numbering it (e.g.
+// "part 1/3"), so that readers can follow a long log across messages. maxBytes
+// must be at least 64, or NewCodeBlockBatcher will panic.
+func NewCodeBlockBatcher(b Batcher, maxBytes int) Batcher {
+	if maxBytes < codeBlockMinBytes {
+		panic(errors.New("slackio: NewCodeBlockBatcher requires a maxBytes of at least 64"))
+	}
+
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			for s := range inCh {
+				pieces := splitCodeBlock(s, maxBytes)
+				for i, piece := range pieces {
+					if len(pieces) > 1 {
+						piece = fmt.Sprintf("part %d/%d\n%s", i+1, len(pieces), piece)
+					}
+					outCh <- piece
+				}
+			}
+			close(outCh)
+
+			outErrCh <- <-inErrCh
+			close(outErrCh)
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// splitCodeBlock splits s into fenced code blocks that will each fit within
+// maxBytes bytes once numbered. Since the length of the numbering depends on
+// the number of pieces, the split is repeated until that number is stable.
+func splitCodeBlock(s string, maxBytes int) []string {
+	const fence = "```"
+	overhead := len(fence+"\n") + len("\n"+fence)
+
+	pieces := splitBytes(s, maxBytes-overhead)
+	for len(pieces) > 1 {
+		header := len(fmt.Sprintf("part %d/%d\n", len(pieces), len(pieces)))
+		next := splitBytes(s, maxBytes-overhead-header)
+		if len(next) <= len(pieces) {
+			pieces = next
+			break
+		}
+		pieces = next
+	}
+
+	for i := range pieces {
+		pieces[i] = fence + "\n" + pieces[i] + "\n" + fence
+	}
+	return pieces
+}
+
+// NewTriggerBatcher returns a Batcher that collects the output of an upstream
+// Batcher until it emits a batch exactly equal to trigger, such as a line
+// containing only a form feed or a marker like "--send--". At that point, the
+// collected batches are joined with newlines and flushed to the output channel
+// as a single batch, and the trigger itself is discarded. Any batches that
+// remain when the upstream Batcher terminates are flushed in the same way.
+func NewTriggerBatcher(b Batcher, trigger string) Batcher {
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			var collected []string
+
+			flush := func() {
+				if len(collected) > 0 {
+					outCh <- strings.Join(collected, "\n")
+				}
+				collected = nil
+			}
+
+			for s := range inCh {
+				if s == trigger {
+					flush()
+				} else {
+					collected = append(collected, s)
+				}
+			}
+			flush()
+			close(outCh)
+
+			outErrCh <- <-inErrCh
+			close(outErrCh)
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// NewTrimBatcher returns a Batcher that removes trailing whitespace from each
+// line of every batch emitted by an upstream Batcher. Batches that consist
+// entirely of whitespace are dropped.
+func NewTrimBatcher(b Batcher) Batcher {
+	return func(r io.Reader) (<-chan string, <-chan error) {
+		inCh, inErrCh := b(r)
+		outCh, outErrCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			for s := range inCh {
+				if s = trimLines(s); s != "" {
+					outCh <- s
+				}
+			}
+			close(outCh)
+
+			outErrCh <- <-inErrCh
+			close(outErrCh)
+		}()
+
+		return outCh, outErrCh
+	}
+}
+
+// trimLines removes trailing whitespace from each line of s, as well as any
+// trailing blank lines.
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
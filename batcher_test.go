@@ -152,6 +152,64 @@ func TestIntervalBatcher(t *testing.T) {
 	}
 }
 
+// testClock is a Clock whose timers fire only when the test fires them.
+type testClock struct {
+	timers chan chan time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{timers: make(chan chan time.Time, 1)}
+}
+
+func (c *testClock) Now() time.Time {
+	return time.Time{}
+}
+
+func (c *testClock) After(_ time.Duration) <-chan time.Time {
+	timer := make(chan time.Time, 1)
+	c.timers <- timer
+	return timer
+}
+
+// fire fires the next timer started with the clock.
+func (c *testClock) fire() {
+	(<-c.timers) <- time.Time{}
+}
+
+func TestIntervalBatcherWithClock(t *testing.T) {
+	// Each batcher runs with its own clock, independent of timeAfter and of
+	// each other.
+	for _, name := range []string{"first", "second"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := &testBatcher{
+				batches: []testBatch{{out: name}, {out: "batch"}},
+			}
+
+			clock := newTestClock()
+			batcher := NewIntervalBatcherWithClock(tb.makeBatcher(), time.Hour, " ", clock)
+			outCh, errCh := batcher(strings.NewReader(""))
+
+			tb.emitNext()
+			tb.emitNext()
+			clock.fire()
+			if s := <-outCh; s != name+" batch" {
+				t.Fatalf("unexpected interval batcher output: %q (expected %q)", s, name+" batch")
+			}
+
+			tb.emitNext()
+			if _, ok := <-outCh; ok {
+				t.Fatal("interval batcher did not close output when upstream did")
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("unexpected interval batcher error: %q", err.Error())
+			}
+		})
+	}
+}
+
 func TestIntervalBatcherHandlesErrors(t *testing.T) {
 	expectedErr := errors.New("test batcher error")
 	tb := &testBatcher{
@@ -186,3 +244,301 @@ func TestIntervalBatcherHandlesErrors(t *testing.T) {
 		t.Fatalf("unexpected interval batcher error: %q", err.Error())
 	}
 }
+
+// staticBatcher returns a Batcher that ignores its input and emits the given
+// batches.
+func staticBatcher(batches ...string) Batcher {
+	return func(_ io.Reader) (<-chan string, <-chan error) {
+		outCh, errCh := make(chan string), make(chan error, 1)
+
+		go func() {
+			for _, batch := range batches {
+				outCh <- batch
+			}
+			close(outCh)
+			close(errCh)
+		}()
+
+		return outCh, errCh
+	}
+}
+
+// collectBatches runs the given Batcher to completion and returns all of its
+// output along with its final error.
+func collectBatches(b Batcher) ([]string, error) {
+	var output []string
+	outCh, errCh := b(strings.NewReader(""))
+
+	for s := range outCh {
+		output = append(output, s)
+	}
+
+	return output, <-errCh
+}
+
+func TestByteSizeBatcher(t *testing.T) {
+	cases := []struct {
+		description string
+		input       []string
+		maxBytes    int
+		output      []string
+	}{
+		{
+			"passes through short batches",
+			[]string{"short", "batches"},
+			10,
+			[]string{"short", "batches"},
+		},
+		{
+			"splits ASCII batches",
+			[]string{"abcdefgh"},
+			3,
+			[]string{"abc", "def", "gh"},
+		},
+		{
+			"splits multibyte batches on character boundaries",
+			// 7 characters, but 21 bytes
+			[]string{"日本語テキスト"},
+			7,
+			[]string{"日本", "語テ", "キス", "ト"},
+		},
+		{
+			"splits mixed-width batches on character boundaries",
+			[]string{"héllo wörld"},
+			4,
+			[]string{"hél", "lo w", "örl", "d"},
+		},
+		{
+			"splits at line breaks where possible",
+			[]string{"one two\nthree four"},
+			12,
+			[]string{"one two", "three four"},
+		},
+		{
+			"removes blank lines at split boundaries",
+			[]string{"aaaa\n\nbbbb\n\ncccc"},
+			5,
+			[]string{"aaaa", "bbbb", "cccc"},
+		},
+		{
+			"emits oversized characters whole",
+			[]string{"😀😀"},
+			2,
+			[]string{"😀", "😀"},
+		},
+		{
+			"drops empty pieces",
+			[]string{"", "\n\n\n\n", "text"},
+			2,
+			[]string{"te", "xt"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			output, err := collectBatches(NewByteSizeBatcher(staticBatcher(tc.input...), tc.maxBytes))
+			if err != nil {
+				t.Fatalf("unexpected byte size batcher error: %v", err)
+			}
+
+			if !reflect.DeepEqual(output, tc.output) {
+				t.Fatalf("unexpected byte size batcher output %#v (expected %#v)", output, tc.output)
+			}
+		})
+	}
+}
+
+func TestTrimBatcher(t *testing.T) {
+	input := []string{"trailing spaces   ", "tabs\t\t", "   \t ", "multiple  \nlines \n\n", "clean"}
+	output, err := collectBatches(NewTrimBatcher(staticBatcher(input...)))
+	if err != nil {
+		t.Fatalf("unexpected trim batcher error: %v", err)
+	}
+
+	expected := []string{"trailing spaces", "tabs", "multiple\nlines", "clean"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("unexpected trim batcher output %#v (expected %#v)", output, expected)
+	}
+}
+
+func TestTriggerBatcher(t *testing.T) {
+	input := []string{"--send--", "first", "second", "--send--", "--send--", "third"}
+	output, err := collectBatches(NewTriggerBatcher(staticBatcher(input...), "--send--"))
+	if err != nil {
+		t.Fatalf("unexpected trigger batcher error: %v", err)
+	}
+
+	expected := []string{"first\nsecond", "third"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("unexpected trigger batcher output %#v (expected %#v)", output, expected)
+	}
+}
+
+// severity returns the prefix of a log line before the first colon.
+func severity(s string) string {
+	return strings.SplitN(s, ":", 2)[0]
+}
+
+func TestGroupByBatcher(t *testing.T) {
+	tb := &testBatcher{
+		batches: []testBatch{
+			{out: "ERROR: disk full"},
+			{out: "INFO: retrying"},
+			{out: "ERROR: write failed"},
+			{out: "INFO: gave up"},
+		},
+	}
+
+	timers := make(chan chan time.Time, len(tb.batches))
+	timeAfter = func(_ time.Duration) <-chan time.Time {
+		timer := make(chan time.Time, 1)
+		timers <- timer
+		return timer
+	}
+	defer func() { timeAfter = time.After }()
+
+	batcher := NewGroupByBatcher(tb.makeBatcher(), severity, time.Second, "\n")
+	outCh, errCh := batcher(strings.NewReader(""))
+
+	for range tb.batches {
+		tb.emitNext()
+	}
+
+	errorTimer, infoTimer := <-timers, <-timers
+
+	infoTimer <- time.Now()
+	if s := <-outCh; s != "INFO: retrying\nINFO: gave up" {
+		t.Fatalf("unexpected group by batcher output: %q", s)
+	}
+
+	errorTimer <- time.Now()
+	if s := <-outCh; s != "ERROR: disk full\nERROR: write failed" {
+		t.Fatalf("unexpected group by batcher output: %q", s)
+	}
+
+	tb.emitNext() // close output channel to stop downstream batcher
+	if _, ok := <-outCh; ok {
+		t.Fatal("group by batcher did not close output when upstream did")
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected group by batcher error: %q", err.Error())
+	}
+}
+
+func TestGroupByBatcherFlushesOnClose(t *testing.T) {
+	input := []string{"ERROR: a", "INFO: b", "ERROR: c", "WARN: d", "INFO: e"}
+	output, err := collectBatches(NewGroupByBatcher(staticBatcher(input...), severity, time.Hour, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected group by batcher error: %v", err)
+	}
+
+	expected := []string{"ERROR: a\nERROR: c", "INFO: b\nINFO: e", "WARN: d"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("unexpected group by batcher output %#v (expected %#v)", output, expected)
+	}
+}
+
+func TestIntervalBatcherBlankLineDelimiter(t *testing.T) {
+	chunks := []string{"first chunk", "second chunk", "third chunk"}
+	batcher := NewByteSizeBatcher(NewIntervalBatcher(staticBatcher(chunks...), time.Hour, "\n\n"), 20)
+
+	output, err := collectBatches(batcher)
+	if err != nil {
+		t.Fatalf("unexpected batcher error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, chunks) {
+		t.Fatalf("unexpected batcher output %#v (expected %#v)", output, chunks)
+	}
+
+	// Without any splitting, the delimiter is preserved between chunks.
+	batcher = NewByteSizeBatcher(NewIntervalBatcher(staticBatcher(chunks...), time.Hour, "\n\n"), 100)
+
+	output, err = collectBatches(batcher)
+	if err != nil {
+		t.Fatalf("unexpected batcher error: %v", err)
+	}
+
+	expected := []string{"first chunk\n\nsecond chunk\n\nthird chunk"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("unexpected batcher output %#v (expected %#v)", output, expected)
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		output      string
+	}{
+		{
+			"leaves short lines untouched",
+			"  a:   b\n\tindented",
+			"  a:   b\n\tindented",
+		},
+		{
+			"wraps long lines",
+			"one two three four five",
+			"one two\nthree four\nfive",
+		},
+		{
+			"keeps the indentation of long lines",
+			"  - one two three four",
+			"  - one\n  two\n  three\n  four",
+		},
+		{
+			"keeps runs of whitespace that are not wrapped at",
+			"a  b\tc one two",
+			"a  b\tc one\ntwo",
+		},
+		{
+			"drops the whitespace at each wrap point",
+			"one   two\t\tthree   ",
+			"one   two\nthree   ",
+		},
+		{
+			"wraps only the lines that need it",
+			"a  b\none two three four\nc  d",
+			"a  b\none two\nthree four\nc  d",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			if output := wrapWords(tc.input, 10); output != tc.output {
+				t.Fatalf("unexpected wrapped output %q (expected %q)", output, tc.output)
+			}
+		})
+	}
+}
+
+func TestCodeBlockBatcher(t *testing.T) {
+	lines := []string{
+		strings.Repeat("a", 40),
+		strings.Repeat("b", 40),
+		strings.Repeat("c", 40),
+	}
+	input := strings.Join(lines, "\n")
+
+	output, err := collectBatches(NewCodeBlockBatcher(staticBatcher("short log", input), 64))
+	if err != nil {
+		t.Fatalf("unexpected code block batcher error: %v", err)
+	}
+
+	expected := []string{
+		"```\nshort log\n```",
+		"part 1/3\n```\n" + lines[0] + "\n```",
+		"part 2/3\n```\n" + lines[1] + "\n```",
+		"part 3/3\n```\n" + lines[2] + "\n```",
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Fatalf("unexpected code block batcher output %#v (expected %#v)", output, expected)
+	}
+
+	for _, s := range output {
+		if len(s) > 64 {
+			t.Errorf("code block batcher output exceeds 64 bytes: %q", s)
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package slackio
 
 import (
+	"context"
 	"errors"
+	"io"
+	"log"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nlopes/slack"
 )
@@ -13,14 +20,248 @@ import (
 // for each Client instance.
 const messageQueueSize = 16
 
+// defaultMaxQueueSize is the maximum size to which a Client's message queue may
+// grow to accommodate subscriptions using PolicyBlock, unless overridden with
+// ClientOptionMaxQueueSize.
+const defaultMaxQueueSize = 1024
+
 // ErrAlreadySubscribed is returned when an attempt is made to subscribe a
 // channel that already has a subscription.
 var ErrAlreadySubscribed = errors.New("slackio: channel already subscribed")
 
+// ErrClientClosed is returned when an attempt is made to send a message using a
+// Client that has been closed.
+var ErrClientClosed = errors.New("slackio: client is closed")
+
+// ErrChannelNotAllowed is returned when an attempt is made to send a message to
+// a channel outside of a Client's allowlist (see ClientOptionAllowedChannels).
+var ErrChannelNotAllowed = errors.New("slackio: sending to channel not allowed")
+
 // ErrNotSubscribed is returned when an attempt is made to unsubscribe a
 // channel that is not currently subscribed.
 var ErrNotSubscribed = errors.New("slackio: channel not subscribed")
 
+// RTMUnsupportedError is returned by NewClientChecked when Slack does not allow
+// the given token to use the real-time API, which is the case for all Slack
+// apps created after Slack stopped supporting it for new apps.
+type RTMUnsupportedError struct {
+	// Err is the original error returned by Slack.
+	Err error
+}
+
+func (e *RTMUnsupportedError) Error() string {
+	return "slackio: this token cannot use Slack's real-time API, which is unavailable to newer Slack apps; " +
+		"use a classic app's bot token, or an app built on Socket Mode or the Events API instead " +
+		"(" + e.Err.Error() + ")"
+}
+
+// Unwrap returns the original error returned by Slack.
+func (e *RTMUnsupportedError) Unwrap() error {
+	return e.Err
+}
+
+// ClientOption configures optional behavior for a Client.
+type ClientOption func(*Client)
+
+// ClientOptionRawEventHandler causes a Client to invoke the given handler for
+// every event received from Slack's real-time API, in the order received and
+// before the Client performs any processing of its own. This allows for access
+// to events that slackio does not otherwise handle. The handler is invoked
+// synchronously within the Client's event loop, and should return promptly.
+func ClientOptionRawEventHandler(handler func(slack.RTMEvent)) ClientOption {
+	return func(c *Client) {
+		c.rawEventHandler = handler
+	}
+}
+
+// ClientOptionDefaultUsername causes messages sent with PostMessage to appear
+// under the given username, unless the message specifies its own.
+func ClientOptionDefaultUsername(username string) ClientOption {
+	return func(c *Client) {
+		c.defaultUsername = username
+	}
+}
+
+// ClientOptionDefaultIconEmoji causes messages sent with PostMessage to use the
+// given emoji (e.g. ":robot_face:") as their icon, unless the message specifies
+// its own.
+func ClientOptionDefaultIconEmoji(emoji string) ClientOption {
+	return func(c *Client) {
+		c.defaultIconEmoji = emoji
+	}
+}
+
+// ClientOptionEdits causes a Client to distribute edits to existing messages
+// as new Messages in its stream, with the Edited field set.
+func ClientOptionEdits() ClientOption {
+	return func(c *Client) {
+		c.edits = true
+	}
+}
+
+// ClientOptionOnReconnect causes a Client to invoke the given callback each
+// time it reconnects to Slack after losing its connection, but not on the
+// initial connection. Subscriptions persist across reconnections, but messages
+// sent while the Client is disconnected are not received; the callback gives
+// applications an opportunity to re-synchronize any external state. The
+// callback is invoked synchronously within the Client's event loop, and should
+// return promptly.
+func ClientOptionOnReconnect(callback func()) ClientOption {
+	return func(c *Client) {
+		c.onReconnect = callback
+	}
+}
+
+// ClientOptionDedupeRedelivery causes a Client to remember the channel and
+// timestamp of the latest size messages that it distributed, and to drop any
+// message that it receives again. Slack can redeliver recent messages after a
+// reconnection, which would otherwise appear twice in the Client's stream. The
+// cache persists across reconnections. Edits (see ClientOptionEdits) are not
+// subject to deduplication. size must be positive, or
+// ClientOptionDedupeRedelivery will panic.
+func ClientOptionDedupeRedelivery(size int) ClientOption {
+	if size <= 0 {
+		panic(errors.New("slackio: ClientOptionDedupeRedelivery requires a positive size"))
+	}
+
+	return func(c *Client) {
+		c.seenMessagesSize = size
+	}
+}
+
+// ClientOptionOnSubscriberLag causes a Client to invoke the given callback
+// whenever a subscriber falls behind the Client's message buffer and is skipped
+// forward, as described in the SubscribeAt documentation. The callback receives
+// the ID of the first skipped message and the ID of the message that the
+// subscriber was skipped to. It is invoked from the subscription's own
+// goroutine, and may be called concurrently for different subscriptions.
+func ClientOptionOnSubscriberLag(callback func(skippedFrom, skippedTo int)) ClientOption {
+	return func(c *Client) {
+		c.onSubscriberLag = callback
+	}
+}
+
+// ClientOptionLogger causes a Client to write its log output to the given
+// logger, rather than to the standard logger provided by the log package.
+func ClientOptionLogger(logger *log.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// ClientOptionDryRun causes a Client to log each message that it would send to
+// Slack instead of sending it. This applies to SendMessage as well as to every
+// method that sends using the Web API, all of which report success. Messages
+// are still received from Slack as usual. PostMessage returns a blank
+// timestamp for each message.
+func ClientOptionDryRun() ClientOption {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+// ClientOptionSerializeWrites causes each Writer that sends through a Client to
+// send all of the output from a single Write before returning, while holding a
+// lock shared by every such Writer for the same channel. This keeps a
+// multi-line block written by one Writer from being interleaved with output
+// from another Writer to the same channel, at the cost of the batching that
+// would otherwise span multiple writes. It has no effect on Writers created
+// with NewRoundRobinWriter.
+func ClientOptionSerializeWrites() ClientOption {
+	return func(c *Client) {
+		c.serializeWrites = true
+	}
+}
+
+// ClientOptionAllowedChannels restricts a Client to sending messages to the
+// channels with the given IDs. Attempts to send elsewhere, whether with
+// SendMessage or with any method that sends using the Web API, fail with
+// ErrChannelNotAllowed. This guards against misdirected output in deployments
+// where a Client is shared between tenants. Receiving messages is unaffected.
+func ClientOptionAllowedChannels(channelIDs ...string) ClientOption {
+	return func(c *Client) {
+		c.allowedChannels = make(map[string]bool, len(channelIDs))
+		for _, id := range channelIDs {
+			c.allowedChannels[id] = true
+		}
+	}
+}
+
+// ClientOptionMaxQueueSize sets the maximum size to which a Client's message
+// queue may grow to accommodate subscriptions using PolicyBlock. If a slow
+// subscriber would cause the queue to grow past this size, the Client logs the
+// event and reverts to the usual skip-forward behavior for all subscribers, to
+// protect the process from unbounded memory growth. n must be at least the
+// normal queue size of 16, or ClientOptionMaxQueueSize will panic.
+func ClientOptionMaxQueueSize(n int) ClientOption {
+	if n < messageQueueSize {
+		panic(errors.New("slackio: maximum queue size is smaller than the normal queue size"))
+	}
+
+	return func(c *Client) {
+		c.maxQueueSize = n
+	}
+}
+
+// ClientOptionEnableExpvar causes a Client to publish information about its
+// internal state through the expvar package, in a map named "slackio". The map
+// includes the length of the message queue, the ID that will be assigned to
+// the next message, and the number of active subscriptions. Since expvar names
+// are global, only one Client in a process should use this option; if more than
+// one does, the most recently created Client is published.
+func ClientOptionEnableExpvar() ClientOption {
+	return func(c *Client) {
+		c.publishExpvar()
+	}
+}
+
+// ClientOptionAttachmentFallback causes a Client to distribute messages that
+// have no text of their own, as is common for messages from integrations, using
+// the fallback text of their first attachment. If the attachment has no
+// fallback text, its main text is used instead.
+func ClientOptionAttachmentFallback() ClientOption {
+	return func(c *Client) {
+		c.attachmentFallback = true
+	}
+}
+
+// sentTimestampsSize is the number of sent messages that a Client using
+// ClientOptionTrackReplies remembers.
+const sentTimestampsSize = 256
+
+// ClientOptionTrackReplies causes a Client to remember the timestamps of the
+// most recent messages that it sent, and to distribute thread replies to those
+// messages alongside other messages in its stream, with the ThreadTimestamp
+// field set. Other thread messages are still ignored. Readers exclude these
+// replies unless ReaderOptionReplies is used.
+func ClientOptionTrackReplies() ClientOption {
+	return func(c *Client) {
+		c.trackReplies = true
+	}
+}
+
+// ClientOptionUnarchiveIfNeeded causes a Client to unarchive a channel when
+// Slack reports that a message sent to it using the Web API could not be
+// delivered because the channel is archived, and then to send the message
+// again. Without this option, an *ArchivedChannelError is returned in this
+// case. Note that Slack only allows some tokens to unarchive channels, and that
+// channel_not_found errors are not handled (see ArchivedChannelError).
+func ClientOptionUnarchiveIfNeeded() ClientOption {
+	return func(c *Client) {
+		c.unarchiveIfNeeded = true
+	}
+}
+
+// ClientOptionResolvePermalinks causes a Client to populate the Permalink field
+// of each incoming Message. This requires an additional Web API request for
+// every message, made synchronously within the Client's event loop. If the
+// request fails, the Message is distributed with a blank Permalink.
+func ClientOptionResolvePermalinks() ClientOption {
+	return func(c *Client) {
+		c.resolvePermalinks = true
+	}
+}
+
 // Client implements an ability to send and receive Slack messages using a
 // real-time API. For readers, it presents a long-running stream of a user's
 // incoming Slack messages that may be consumed using multiple independent
@@ -32,95 +273,600 @@ var ErrNotSubscribed = errors.New("slackio: channel not subscribed")
 // instances.
 type Client struct {
 	rtm *slack.RTM
+	api webAPI
 
 	wg   sync.WaitGroup
 	done chan struct{}
 
+	// closed is set atomically to 1 when the Client begins to close.
+	closed int32
+
+	// closeLock is held while the Client begins to close, and while starting
+	// goroutines that are tracked by wg after the Client has started, so that
+	// none is started once Close is waiting for them.
+	closeLock sync.Mutex
+
+	// hasConnected is set on the first connection to Slack, and is only
+	// accessed within the event loop.
+	hasConnected bool
+
 	messages      []Message
 	messagesLock  sync.RWMutex
 	messagesCond  *sync.Cond
 	nextMessageID int
 
+	// channelCounts is protected by messagesLock.
+	channelCounts map[string]int
+
 	subs     map[chan<- Message]*subscription
 	subsLock sync.Mutex
+
+	// blockingSubs holds the subscriptions that use PolicyBlock. It has its own
+	// lock so that distribute may inspect it without waiting on subsLock, which
+	// is held while subscriptions stop.
+	blockingSubs     map[*subscription]struct{}
+	blockingSubsLock sync.Mutex
+	maxQueueSize     int
+
+	starSubs      map[chan<- StarEvent]struct{}
+	channelSubs   map[chan<- ChannelEvent]struct{}
+	pinSubs       map[chan<- PinEvent]struct{}
+	eventSubsLock sync.Mutex
+
+	connectionStates chan ConnectionState
+
+	selfID     string
+	selfIDLock sync.Mutex
+
+	emailChannels     map[string]string
+	emailChannelsLock sync.Mutex
+
+	sendInterval  time.Duration
+	sendQueue     sendQueue
+	sendQueueSeq  int
+	sendQueueLock sync.Mutex
+	sendReady     chan struct{}
+
+	// seenMessages and seenMessageOrder are protected by messagesLock.
+	seenMessagesSize int
+	seenMessages     map[string]struct{}
+	seenMessageOrder []string
+
+	// writers holds the open Writers that send through this Client, for
+	// FlushAll.
+	writers     map[*Writer]struct{}
+	writersLock sync.Mutex
+
+	// channelLocks serialize writes from different Writers to the same channel,
+	// if the Client is configured to do so.
+	serializeWrites  bool
+	channelLocks     map[string]*sync.Mutex
+	channelLocksLock sync.Mutex
+
+	sentTimestamps     map[string]struct{}
+	sentTimestampOrder []string
+	sentTimestampsLock sync.Mutex
+
+	// apiURL, if non-empty, overrides the base URL of the Slack API.
+	apiURL string
+
+	// firstConnect, if non-nil, receives the outcome of the first connection
+	// attempt for NewClientChecked. It is only accessed by the event loop once
+	// the Client has started.
+	firstConnect chan<- error
+
+	// apiLimiter, if non-nil, paces calls to the Web API.
+	apiLimiter *rateLimiter
+
+	logger             *log.Logger
+	dryRun             bool
+	edits              bool
+	attachmentFallback bool
+	trackReplies       bool
+	resolvePermalinks  bool
+	unarchiveIfNeeded  bool
+	allowedChannels    map[string]bool
+	rawEventHandler    func(slack.RTMEvent)
+	onReconnect        func()
+	onSubscriberLag    func(skippedFrom, skippedTo int)
+	defaultUsername    string
+	defaultIconEmoji   string
 }
 
 // NewClient returns a new Client and connects it to Slack using the given API
 // token. Invalid API tokens will result in a panic while attempting to
-// establish the connection.
-func NewClient(apiToken string) *Client {
+// establish the connection. Any provided options are applied in order.
+//
+// See NewClientChecked for a version that returns an error for tokens that
+// Slack rejects outright, rather than panicking.
+func NewClient(apiToken string, opts ...ClientOption) *Client {
 	if apiToken == "" {
 		panic("slackio: Client requires a non-blank API token")
 	}
 
-	c := initClient()
+	c := initClient(opts...)
+	c.start(apiToken)
+	return c
+}
+
+// NewClientChecked returns a new Client as NewClient does, but first checks the
+// token with Slack and waits for the outcome of the Client's first connection
+// attempt. If Slack rejects the token outright, NewClientChecked returns an
+// error rather than a Client. In particular, an *RTMUnsupportedError is
+// returned for tokens belonging to Slack apps that cannot use the real-time
+// API. Other failures, such as network errors, are retried in the background as
+// usual.
+func NewClientChecked(apiToken string, opts ...ClientOption) (*Client, error) {
+	if apiToken == "" {
+		return nil, errors.New("slackio: Client requires a non-blank API token")
+	}
+
+	c := initClient(opts...)
+
+	if _, err := c.newAPI(apiToken).AuthTest(); err != nil && isInvalidAuth(err) {
+		c.Close()
+		return nil, errInvalidAuth
+	}
 
-	api := slack.New(apiToken)
+	firstConnect := make(chan error, 1)
+	c.firstConnect = firstConnect
+	c.start(apiToken)
+
+	if err := <-firstConnect; err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// errInvalidAuth reports that Slack rejected a Client's API token.
+var errInvalidAuth = errors.New("slackio: Slack API credentials are invalid")
+
+// isInvalidAuth reports whether err is Slack's response to an API token that
+// it does not accept.
+func isInvalidAuth(err error) bool {
+	switch err.Error() {
+	case "invalid_auth", "account_inactive", "not_authed":
+		return true
+	}
+	return false
+}
+
+// newAPI returns a Slack API client for the given token.
+func (c *Client) newAPI(apiToken string) *slack.Client {
+	if c.apiURL == "" {
+		return slack.New(apiToken)
+	}
+	return slack.New(apiToken, slack.OptionAPIURL(c.apiURL))
+}
+
+// start connects this Client to Slack using the given API token, and begins
+// processing events from the real-time API.
+func (c *Client) start(apiToken string) {
+	api := c.newAPI(apiToken)
+	c.api = api
 	c.rtm = api.NewRTM()
 	go c.rtm.ManageConnection()
 
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		for {
-			select {
-			case evt := <-c.rtm.IncomingEvents:
-				switch data := evt.Data.(type) {
-				case *slack.InvalidAuthEvent:
-					panic(errors.New("slackio: Slack API credentials are invalid"))
-
-				case *slack.MessageEvent:
-					c.distribute(data)
-				}
-
-			case <-c.done:
-				return
-			}
-		}
+		c.processEvents(c.rtm.IncomingEvents)
 	}()
+}
 
-	return c
+// reportFirstConnect delivers the outcome of this Client's first connection
+// attempt to NewClientChecked, if it is waiting for one.
+func (c *Client) reportFirstConnect(err error) {
+	if c.firstConnect != nil {
+		c.firstConnect <- err
+		c.firstConnect = nil
+	}
 }
 
-// initClient returns a Client with basic fields initialized. It mainly helps
-// remove a bit of boilerplate from tests.
-func initClient() *Client {
+// initClient returns a Client with basic fields initialized and the given
+// options applied. It mainly helps remove a bit of boilerplate from tests.
+func initClient(opts ...ClientOption) *Client {
 	c := &Client{}
 
 	c.done = make(chan struct{})
 	c.messagesCond = sync.NewCond(c.messagesLock.RLocker())
 	c.subs = make(map[chan<- Message]*subscription)
+	c.blockingSubs = make(map[*subscription]struct{})
+	c.maxQueueSize = defaultMaxQueueSize
+	c.starSubs = make(map[chan<- StarEvent]struct{})
+	c.channelSubs = make(map[chan<- ChannelEvent]struct{})
+	c.pinSubs = make(map[chan<- PinEvent]struct{})
+	c.connectionStates = make(chan ConnectionState, connectionStatesSize)
+	c.sentTimestamps = make(map[string]struct{})
+	c.seenMessages = make(map[string]struct{})
+	c.channelCounts = make(map[string]int)
+	c.writers = make(map[*Writer]struct{})
+	c.channelLocks = make(map[string]*sync.Mutex)
+	c.emailChannels = make(map[string]string)
+
+	for _, opt := range opts {
+		opt(c)
+	}
 
+	c.startSendQueue()
 	return c
 }
 
+// processEvents handles incoming events from Slack until the Client is closed.
+func (c *Client) processEvents(events <-chan slack.RTMEvent) {
+	for {
+		select {
+		case evt := <-events:
+			c.handleEvent(evt)
+
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// handleEvent processes a single event from Slack.
+func (c *Client) handleEvent(evt slack.RTMEvent) {
+	if c.rawEventHandler != nil {
+		c.rawEventHandler(evt)
+	}
+
+	switch data := evt.Data.(type) {
+	case *slack.InvalidAuthEvent:
+		if c.firstConnect != nil {
+			c.reportFirstConnect(errInvalidAuth)
+			return
+		}
+		panic(errInvalidAuth)
+
+	case *slack.ConnectionErrorEvent:
+		// Slack refuses to connect tokens that cannot use the real-time API, but
+		// the connection manager treats this like any other error and retries.
+		if data.ErrorObj != nil && data.ErrorObj.Error() == "not_allowed_token_type" {
+			c.reportFirstConnect(&RTMUnsupportedError{Err: data.ErrorObj})
+		} else {
+			c.reportFirstConnect(nil)
+		}
+
+	case *slack.ConnectingEvent:
+		c.notifyConnectionState(Connecting)
+
+	case *slack.ConnectedEvent:
+		if data.Info != nil && data.Info.User != nil {
+			c.setSelfID(data.Info.User.ID)
+		}
+		c.notifyConnectionState(Connected)
+		c.reportFirstConnect(nil)
+		if c.hasConnected && c.onReconnect != nil {
+			c.onReconnect()
+		}
+		c.hasConnected = true
+
+	case *slack.DisconnectedEvent:
+		c.notifyConnectionState(Disconnected)
+
+	case *slack.AckMessage:
+		// The real-time API acknowledges each message that we send.
+		c.recordSent(data.Timestamp)
+
+	case *slack.MessageEvent:
+		c.distribute(data)
+
+	case *slack.StarAddedEvent:
+		c.distributeStar(true, data.User, data.Item)
+
+	case *slack.StarRemovedEvent:
+		c.distributeStar(false, data.User, data.Item)
+
+	case *slack.PinAddedEvent:
+		c.distributePin(true, data.User, data.Channel, data.Item)
+
+	case *slack.PinRemovedEvent:
+		c.distributePin(false, data.User, data.Channel, data.Item)
+
+	case *slack.ChannelRenameEvent:
+		c.distributeChannelEvent(ChannelEvent{
+			Kind:      ChannelRenamed,
+			ChannelID: data.Channel.ID,
+			NewName:   data.Channel.Name,
+		})
+
+	case *slack.ChannelArchiveEvent:
+		c.distributeChannelEvent(ChannelEvent{
+			Kind:      ChannelArchived,
+			ChannelID: data.Channel,
+		})
+
+	// The real-time API reports changes to private channels as group events.
+	case *slack.GroupRenameEvent:
+		c.distributeChannelEvent(ChannelEvent{
+			Kind:      ChannelRenamed,
+			ChannelID: data.Group.ID,
+			NewName:   data.Group.Name,
+		})
+
+	case *slack.GroupArchiveEvent:
+		c.distributeChannelEvent(ChannelEvent{
+			Kind:      ChannelArchived,
+			ChannelID: data.Channel,
+		})
+	}
+}
+
 // distribute pushes non-empty messages from the main body of a Slack channel
 // onto the queue for subscriber distribution.
 func (c *Client) distribute(m *slack.MessageEvent) {
-	if m.Type != "message" ||
-		m.ReplyTo > 0 ||
-		m.ThreadTimestamp != "" ||
-		m.Text == "" {
+	msg, ok := c.messageFromEvent(m)
+	if !ok {
 		return
 	}
 
+	if c.resolvePermalinks {
+		ts := m.Timestamp
+		if m.SubMessage != nil {
+			ts = m.SubMessage.Timestamp
+		}
+
+		// A failure here is not worth holding back the message for.
+		msg.Permalink, _ = c.webAPIClient().GetPermalink(&slack.PermalinkParameters{
+			Channel: m.Channel,
+			Ts:      ts,
+		})
+	}
+
 	c.messagesLock.Lock()
 	defer c.messagesLock.Unlock()
 
-	c.messages = append(c.messages, Message{
-		ID:        c.nextMessageID,
-		ChannelID: m.Channel,
-		Text:      m.Text,
-	})
-
-	if len(c.messages) > messageQueueSize {
-		c.messages = c.messages[1:]
+	if c.isRedelivery(msg) {
+		return
 	}
 
+	msg.ID = c.nextMessageID
+	c.messages = append(c.messages, msg)
+	c.channelCounts[msg.ChannelID]++
+	c.trimMessages()
+
 	c.nextMessageID++
 	c.messagesCond.Broadcast()
 }
 
+// isRedelivery reports whether msg has already been distributed, if the Client
+// is configured to dedupe redeliveries, and otherwise remembers it. It must be
+// called with messagesLock held.
+func (c *Client) isRedelivery(msg Message) bool {
+	if c.seenMessagesSize <= 0 || msg.ClientMsgID == "" {
+		return false
+	}
+
+	if _, ok := c.seenMessages[msg.ClientMsgID]; ok {
+		return true
+	}
+
+	c.seenMessages[msg.ClientMsgID] = struct{}{}
+	c.seenMessageOrder = append(c.seenMessageOrder, msg.ClientMsgID)
+
+	if len(c.seenMessageOrder) > c.seenMessagesSize {
+		delete(c.seenMessages, c.seenMessageOrder[0])
+		c.seenMessageOrder = c.seenMessageOrder[1:]
+	}
+	return false
+}
+
+// trimMessages removes old messages from the queue, retaining any that a
+// subscription using PolicyBlock has yet to receive as long as the queue does
+// not exceed its maximum size. It must be called with messagesLock held.
+func (c *Client) trimMessages() {
+	if len(c.messages) <= messageQueueSize {
+		return
+	}
+
+	if len(c.messages) > c.maxQueueSize {
+		c.logf("slackio: message queue exceeded maximum size of %d; skipping blocked subscribers forward", c.maxQueueSize)
+		c.messages = c.messages[len(c.messages)-messageQueueSize:]
+		return
+	}
+
+	start := len(c.messages) - messageQueueSize
+	if oldest, ok := c.oldestBlockingPosition(c.messages[0].ID); ok && oldest-c.messages[0].ID < start {
+		start = oldest - c.messages[0].ID
+	}
+
+	if start > 0 {
+		c.messages = c.messages[start:]
+	}
+}
+
+// oldestBlockingPosition returns the earliest position at or after head of any
+// subscription using PolicyBlock, and false if there are no such subscriptions.
+// A subscription positioned before head has already been skipped forward, such
+// as by the queue cap fallback, and will resume from the start of the queue no
+// matter how much of it is trimmed.
+func (c *Client) oldestBlockingPosition(head int) (int, bool) {
+	c.blockingSubsLock.Lock()
+	defer c.blockingSubsLock.Unlock()
+
+	oldest, ok := 0, false
+	for sub := range c.blockingSubs {
+		pos := sub.position()
+		if pos < head {
+			continue
+		}
+		if !ok || pos < oldest {
+			oldest, ok = pos, true
+		}
+	}
+
+	return oldest, ok
+}
+
+// messageFromEvent converts a Slack message event to a Message, and reports
+// whether the Message should be distributed to subscribers.
+func (c *Client) messageFromEvent(m *slack.MessageEvent) (Message, bool) {
+	if m.Type != "message" || m.ReplyTo > 0 {
+		return Message{}, false
+	}
+
+	if m.SubType == "message_changed" {
+		return c.editFromEvent(m)
+	}
+
+	text := m.Text
+	if text == "" && c.attachmentFallback {
+		text = attachmentText(m.Attachments)
+	}
+
+	if text == "" {
+		return Message{}, false
+	}
+
+	if m.ThreadTimestamp != "" && !c.isReplyToSent(m) {
+		return Message{}, false
+	}
+
+	return Message{
+		ChannelID:   m.Channel,
+		ChannelType: ChannelTypeFromID(m.Channel),
+		UserID:      m.User,
+		Text:        text,
+		Timestamp:   m.Timestamp,
+		ClientMsgID: clientMsgID(m.Channel, m.Timestamp),
+		TeamID:      m.Team,
+		Reactions:   reactionCounts(m.Reactions),
+		Blocks:      m.Blocks.BlockSet,
+
+		ThreadTimestamp: m.ThreadTimestamp,
+	}, true
+}
+
+// clientMsgID returns the ClientMsgID for a message with the given channel and
+// timestamp.
+func clientMsgID(channelID, ts string) string {
+	if ts == "" {
+		return ""
+	}
+	return channelID + ":" + ts
+}
+
+// recordSent remembers the timestamp of a message that this Client sent, if
+// the Client is configured to track replies.
+func (c *Client) recordSent(ts string) {
+	if !c.trackReplies || ts == "" {
+		return
+	}
+
+	c.sentTimestampsLock.Lock()
+	defer c.sentTimestampsLock.Unlock()
+
+	if _, ok := c.sentTimestamps[ts]; ok {
+		return
+	}
+
+	c.sentTimestamps[ts] = struct{}{}
+	c.sentTimestampOrder = append(c.sentTimestampOrder, ts)
+
+	if len(c.sentTimestampOrder) > sentTimestampsSize {
+		delete(c.sentTimestamps, c.sentTimestampOrder[0])
+		c.sentTimestampOrder = c.sentTimestampOrder[1:]
+	}
+}
+
+// isReplyToSent reports whether m is a thread reply to a message that this
+// Client sent, if the Client is configured to track replies.
+func (c *Client) isReplyToSent(m *slack.MessageEvent) bool {
+	if !c.trackReplies || m.ThreadTimestamp == m.Timestamp {
+		return false
+	}
+
+	c.sentTimestampsLock.Lock()
+	defer c.sentTimestampsLock.Unlock()
+
+	_, ok := c.sentTimestamps[m.ThreadTimestamp]
+	return ok
+}
+
+// editFromEvent converts a message_changed event to a Message, if the Client
+// is configured to distribute edits. Changes that do not affect the text of a
+// message (e.g. link unfurls) are not considered edits.
+func (c *Client) editFromEvent(m *slack.MessageEvent) (Message, bool) {
+	if !c.edits ||
+		m.SubMessage == nil ||
+		m.SubMessage.ThreadTimestamp != "" ||
+		m.SubMessage.Text == "" {
+		return Message{}, false
+	}
+
+	var oldText string
+	if m.PreviousMessage != nil {
+		oldText = m.PreviousMessage.Text
+		if oldText == m.SubMessage.Text {
+			return Message{}, false
+		}
+	}
+
+	teamID := m.SubMessage.Team
+	if teamID == "" {
+		teamID = m.Team
+	}
+
+	return Message{
+		ChannelID:   m.Channel,
+		ChannelType: ChannelTypeFromID(m.Channel),
+		UserID:      m.SubMessage.User,
+		Text:        m.SubMessage.Text,
+		Timestamp:   m.SubMessage.Timestamp,
+		TeamID:      teamID,
+		Reactions:   reactionCounts(m.SubMessage.Reactions),
+		Blocks:      m.SubMessage.Blocks.BlockSet,
+		Edited:      true,
+		OldText:     oldText,
+		NewText:     m.SubMessage.Text,
+	}, true
+}
+
+// attachmentText returns the fallback text of the first attachment, or its
+// main text if it has no fallback. It returns a blank string if there are no
+// attachments.
+func attachmentText(attachments []slack.Attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+
+	if attachments[0].Fallback != "" {
+		return attachments[0].Fallback
+	}
+	return attachments[0].Text
+}
+
+// reactionCounts maps the name of each reaction to its count, or returns nil if
+// there are no reactions.
+func reactionCounts(reactions []slack.ItemReaction) map[string]int {
+	if len(reactions) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int, len(reactions))
+	for _, r := range reactions {
+		counts[r.Name] = r.Count
+	}
+	return counts
+}
+
+// copyReactions returns a copy of a map of reaction counts, or nil if there are
+// no reactions.
+func copyReactions(reactions map[string]int) map[string]int {
+	if reactions == nil {
+		return nil
+	}
+
+	copied := make(map[string]int, len(reactions))
+	for name, count := range reactions {
+		copied[name] = count
+	}
+	return copied
+}
+
 // Subscribe creates a new subscription for the given channel within this
 // Client, starting immediately after the latest message in the client's
 // overall message stream. See the SubscribeAt documentation for more details.
@@ -151,6 +897,34 @@ func (c *Client) Subscribe(ch chan<- Message) error {
 // If the given channel already has an active subscription,
 // ErrAlreadySubscribed will be returned.
 func (c *Client) SubscribeAt(id int, ch chan<- Message) error {
+	_, err := c.subscribe(id, ch, nil, PolicySkip)
+	return err
+}
+
+// SubscribeWithHandle creates a new subscription for the given channel within
+// this Client, exactly as SubscribeAt does, and returns a Subscription handle
+// that may be used to pause and resume delivery.
+func (c *Client) SubscribeWithHandle(id int, ch chan<- Message) (*Subscription, error) {
+	sub, err := c.subscribe(id, ch, nil, PolicySkip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Subscription{sub: sub}, nil
+}
+
+// SubscribeAtWithPolicy creates a new subscription for the given channel within
+// this Client, exactly as SubscribeAt does, but with the given policy for
+// handling a subscriber that falls behind.
+func (c *Client) SubscribeAtWithPolicy(id int, ch chan<- Message, policy SubscriptionPolicy) error {
+	_, err := c.subscribe(id, ch, nil, policy)
+	return err
+}
+
+// subscribe implements SubscribeAt, and returns the new subscription for
+// callers that need it. If filter is non-nil, only messages that it accepts
+// are delivered to ch.
+func (c *Client) subscribe(id int, ch chan<- Message, filter func(Message) bool, policy SubscriptionPolicy) (*subscription, error) {
 	if id < 0 {
 		c.messagesLock.RLock()
 		id = c.nextMessageID
@@ -161,11 +935,76 @@ func (c *Client) SubscribeAt(id int, ch chan<- Message) error {
 	defer c.subsLock.Unlock()
 
 	if _, ok := c.subs[ch]; ok {
-		return ErrAlreadySubscribed
+		return nil, ErrAlreadySubscribed
 	}
 
-	c.subs[ch] = newSubscription(c, id, ch)
-	return nil
+	sub := newSubscription(c, id, ch, filter)
+	c.subs[ch] = sub
+
+	if policy == PolicyBlock {
+		c.blockingSubsLock.Lock()
+		c.blockingSubs[sub] = struct{}{}
+		c.blockingSubsLock.Unlock()
+	}
+
+	return sub, nil
+}
+
+// SubscribeMentions creates a new subscription for the given channel within
+// this Client, exactly as Subscribe does, except that only messages that
+// mention the user with the given ID (e.g. a bot's own user ID) are delivered.
+// The subscription is terminated with Unsubscribe as usual.
+func (c *Client) SubscribeMentions(userID string, ch chan<- Message) error {
+	mention := "<@" + userID + ">"
+	_, err := c.subscribe(-1, ch, func(m Message) bool {
+		return strings.Contains(m.Text, mention)
+	}, PolicySkip)
+	return err
+}
+
+// SubscribeCallback creates a new subscription within this Client, starting at
+// the given ID as SubscribeAt does, that invokes fn for each message on an
+// internal goroutine. Messages are processed one at a time, in order.
+//
+// The returned cancel function terminates the subscription. After cancel
+// returns, fn will not be called again; if fn is running when cancel is
+// called, cancel waits for it to return. For this reason, fn must not call
+// cancel itself. Subsequent calls to cancel, and calls after the Client is
+// closed, have no effect on the Client.
+func (c *Client) SubscribeCallback(id int, fn func(Message)) (cancel func()) {
+	ch := make(chan Message)
+	done := make(chan struct{})
+
+	// A new channel cannot already be subscribed.
+	if _, err := c.subscribe(id, ch, nil, PolicySkip); err != nil {
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case msg := <-ch:
+				fn(msg)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// Once unsubscribed, nothing else can be sent on ch, so the goroutine
+			// finishes with at most the call to fn that is already in progress.
+			// An error means that UnsubscribeAll or Close already did this for us.
+			c.Unsubscribe(ch)
+			close(done)
+			wg.Wait()
+		})
+	}
 }
 
 // Unsubscribe terminates the subscription for the given channel within this
@@ -181,30 +1020,335 @@ func (c *Client) Unsubscribe(ch chan<- Message) error {
 	}
 
 	c.subs[ch].stop()
+
+	c.blockingSubsLock.Lock()
+	delete(c.blockingSubs, c.subs[ch])
+	c.blockingSubsLock.Unlock()
+
 	delete(c.subs, ch)
 	return nil
 }
 
-// SendMessage sends the given Message to its associated Slack channel.
-func (c *Client) SendMessage(m Message) {
+// SubscriptionDone returns a channel that is closed when the subscription for
+// the given channel within this Client ends, whether by Unsubscribe,
+// UnsubscribeAll, or Close. If the given channel is not subscribed, the
+// returned channel is already closed.
+func (c *Client) SubscriptionDone(ch chan<- Message) <-chan struct{} {
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	if sub, ok := c.subs[ch]; ok {
+		return sub.done
+	}
+
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// UnsubscribeAll terminates every subscription within this Client. After
+// UnsubscribeAll returns, no previously subscribed channel will receive any
+// further messages, and each may safely be closed.
+func (c *Client) UnsubscribeAll() {
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	for ch, sub := range c.subs {
+		sub.stop()
+		delete(c.subs, ch)
+	}
+
+	c.blockingSubsLock.Lock()
+	c.blockingSubs = make(map[*subscription]struct{})
+	c.blockingSubsLock.Unlock()
+}
+
+// EarliestBufferedID returns the ID of the oldest message still retained in
+// this Client's message buffer, which is the earliest point at which
+// SubscribeAt can begin a subscription without skipping forward. If the buffer
+// is empty, EarliestBufferedID returns false.
+func (c *Client) EarliestBufferedID() (int, bool) {
+	c.messagesLock.RLock()
+	defer c.messagesLock.RUnlock()
+
+	if len(c.messages) == 0 {
+		return 0, false
+	}
+
+	return c.messages[0].ID, true
+}
+
+// MessageCounts returns the number of messages that this Client has
+// distributed from each channel since it was created, keyed by channel ID. The
+// returned map is a copy that the caller may modify.
+func (c *Client) MessageCounts() map[string]int {
+	c.messagesLock.RLock()
+	defer c.messagesLock.RUnlock()
+
+	counts := make(map[string]int, len(c.channelCounts))
+	for id, n := range c.channelCounts {
+		counts[id] = n
+	}
+	return counts
+}
+
+// SubscriptionCount returns the number of active subscriptions within this
+// Client. It is intended for diagnostics, such as detecting leaked
+// subscriptions.
+func (c *Client) SubscriptionCount() int {
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	return len(c.subs)
+}
+
+// SubscriptionIDs returns the current position of each active subscription
+// within this Client, in ascending order. Each position is the ID of the next
+// message that the subscription will attempt to deliver. It is intended for
+// diagnostics, such as identifying subscribers that have fallen behind.
+func (c *Client) SubscriptionIDs() []int {
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	ids := make([]int, 0, len(c.subs))
+	for _, sub := range c.subs {
+		ids = append(ids, sub.position())
+	}
+
+	sort.Ints(ids)
+	return ids
+}
+
+// SendMessage queues the given Message for delivery to its associated Slack
+// channel over the real-time API. It does not wait for Slack to accept the
+// Message, but returns ErrClientClosed if the Client has been closed, or
+// ErrChannelNotAllowed if the channel is outside of the Client's allowlist.
+func (c *Client) SendMessage(m Message) error {
+	if err := c.checkSend(m.ChannelID); err != nil {
+		return err
+	}
+
+	if c.sendInterval > 0 {
+		c.enqueueSend(m)
+	} else {
+		c.deliver(m)
+	}
+
+	return nil
+}
+
+// deliver sends m over the real-time API, or logs it in dry-run mode.
+func (c *Client) deliver(m Message) {
+	if c.dryRun {
+		c.logDryRun(m.ChannelID, m.Text)
+		return
+	}
+
 	msg := c.rtm.NewOutgoingMessage(m.Text, m.ChannelID)
+	msg.ThreadTimestamp = m.ThreadTimestamp
 	c.rtm.SendMessage(msg)
 }
 
+// checkSend returns an error if this Client may not send a message to the
+// channel with the given ID.
+func (c *Client) checkSend(channelID string) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	if c.allowedChannels != nil && !c.allowedChannels[channelID] {
+		return ErrChannelNotAllowed
+	}
+
+	return nil
+}
+
+// isClosed reports whether this Client has begun to close.
+func (c *Client) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) != 0
+}
+
+// SelfID returns the user ID under which this Client is authenticated to
+// Slack, which for a bot token is the ID of the bot user. This allows, for
+// example, a program to ignore its own messages or detect mentions of itself.
+// The ID is learned when the Client first connects to Slack, and SelfID
+// returns false if it is not yet known.
+func (c *Client) SelfID() (string, bool) {
+	c.selfIDLock.Lock()
+	defer c.selfIDLock.Unlock()
+	return c.selfID, c.selfID != ""
+}
+
+func (c *Client) setSelfID(id string) {
+	c.selfIDLock.Lock()
+	defer c.selfIDLock.Unlock()
+	c.selfID = id
+}
+
+// logf writes a message to this Client's logger.
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
+
+// logDryRun logs text that would have been sent to a Slack channel if the
+// Client were not in dry-run mode.
+func (c *Client) logDryRun(channelID, text string) {
+	c.logf("slackio: dry run: would send to %s: %q", channelID, text)
+}
+
+// FlushAll flushes every open Writer that sends through this Client, as if by
+// calling Sync on each, so that an application can send all of its pending
+// output with a single call before it exits (e.g. from a SIGTERM handler). It
+// returns the first error encountered, after flushing every Writer regardless.
+// As with Sync, FlushAll guarantees only that each send was issued; messages
+// sent with SendMessage may still be waiting in the Client's send queue (see
+// ClientOptionSendInterval).
+func (c *Client) FlushAll() error {
+	c.writersLock.Lock()
+	writers := make([]*Writer, 0, len(c.writers))
+	for w := range c.writers {
+		writers = append(writers, w)
+	}
+	c.writersLock.Unlock()
+
+	var firstErr error
+	for _, w := range writers {
+		// A Writer that closes concurrently has already flushed its output.
+		if err := w.Sync(); err != nil && err != io.ErrClosedPipe && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// registerWriter adds w to the set of Writers that FlushAll flushes.
+func (c *Client) registerWriter(w *Writer) {
+	c.writersLock.Lock()
+	defer c.writersLock.Unlock()
+	c.writers[w] = struct{}{}
+}
+
+// channelLock returns the lock that serializes writes to the channel with the
+// given ID.
+func (c *Client) channelLock(channelID string) *sync.Mutex {
+	c.channelLocksLock.Lock()
+	defer c.channelLocksLock.Unlock()
+
+	lock, ok := c.channelLocks[channelID]
+	if !ok {
+		lock = new(sync.Mutex)
+		c.channelLocks[channelID] = lock
+	}
+	return lock
+}
+
+// unregisterWriter removes w from the set of Writers that FlushAll flushes.
+func (c *Client) unregisterWriter(w *Writer) {
+	c.writersLock.Lock()
+	defer c.writersLock.Unlock()
+	delete(c.writers, w)
+}
+
 // Close terminates all subscriptions within this Client and disconnects from
-// Slack. The behavior of Subscribe, SubscribeAt, and Unsubscribe for a closed
-// Client is undefined.
+// Slack. After Close, Unsubscribe returns ErrNotSubscribed for every channel.
+// The behavior of Subscribe and SubscribeAt for a closed Client is undefined.
 func (c *Client) Close() error {
+	c.stopEvents()
+	return c.shutdown()
+}
+
+// CloseGraceful closes this Client as Close does, but first gives subscribers
+// an opportunity to finish receiving messages. It stops processing new events
+// from Slack, then waits until every subscription has delivered all messages
+// in the Client's buffer and every subscribed channel's own buffer is empty,
+// or until ctx is done. Note that a paused subscription will not drain, and
+// will cause CloseGraceful to wait for ctx.
+//
+// The Client is fully closed when CloseGraceful returns. If ctx was done
+// before subscribers finished draining, its error is returned.
+func (c *Client) CloseGraceful(ctx context.Context) error {
+	c.stopEvents()
+
+	err := c.waitDrained(ctx)
+	if closeErr := c.shutdown(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// drainPollInterval is the interval at which CloseGraceful checks whether
+// subscribers have drained.
+const drainPollInterval = 10 * time.Millisecond
+
+// waitDrained blocks until all subscribers have received every buffered
+// message, or until ctx is done.
+func (c *Client) waitDrained(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for !c.drained() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// drained reports whether all subscribers have received every buffered
+// message.
+func (c *Client) drained() bool {
+	c.messagesLock.RLock()
+	next := c.nextMessageID
+	c.messagesLock.RUnlock()
+
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	for ch, sub := range c.subs {
+		if sub.position() < next || len(ch) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// stopEvents stops the processing of events from Slack.
+func (c *Client) stopEvents() {
+	c.closeLock.Lock()
+	atomic.StoreInt32(&c.closed, 1)
 	close(c.done)
+	c.closeLock.Unlock()
+
 	c.wg.Wait()
+	close(c.connectionStates)
+}
 
+// shutdown terminates all subscriptions and disconnects from Slack.
+func (c *Client) shutdown() error {
 	c.subsLock.Lock()
 	defer c.subsLock.Unlock()
 
-	for _, sub := range c.subs {
+	// As with UnsubscribeAll, the subscriptions are forgotten once stopped, so
+	// that a later Unsubscribe reports ErrNotSubscribed rather than stopping
+	// them a second time.
+	for ch, sub := range c.subs {
 		sub.stop()
+		delete(c.subs, ch)
 	}
 
+	c.blockingSubsLock.Lock()
+	c.blockingSubs = make(map[*subscription]struct{})
+	c.blockingSubsLock.Unlock()
+
 	// Unblock any subscribers waiting for a new message and allow them to
 	// terminate.
 	c.messagesCond.Broadcast()
@@ -0,0 +1,153 @@
+package slackio
+
+import (
+	"io"
+	"sync"
+)
+
+// Demux splits a single subscription to a ReadClient into independent streams
+// of text for individual Slack channels. Each stream is formatted in the same
+// way as the output of a Reader.
+//
+// Streams are created on demand. Messages for channels that do not have a
+// stream are discarded. Note that streams share a single subscription, so a
+// stream that is not being read will eventually block the delivery of messages
+// to all others. Closing a stream stops delivery to that stream alone; the
+// shared subscription ends when the Demux is closed, or when its last open
+// stream is closed.
+type Demux struct {
+	client ReadClient
+	msgCh  chan Message
+	wg     sync.WaitGroup
+
+	streams     map[string]*demuxStream
+	streamsLock sync.Mutex
+	closed      bool
+	closeOnce   sync.Once
+}
+
+// NewDemux returns a new Demux, subscribed to the given client.
+func NewDemux(client ReadClient) (*Demux, error) {
+	d := &Demux{
+		client:  client,
+		msgCh:   make(chan Message, 1),
+		streams: make(map[string]*demuxStream),
+	}
+
+	if err := d.client.Subscribe(d.msgCh); err != nil {
+		return nil, err
+	}
+
+	// As with Reader, the stream channel will be drained until it is closed.
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		for msg := range d.msgCh {
+			d.streamsLock.Lock()
+			stream := d.streams[msg.ChannelID]
+			d.streamsLock.Unlock()
+
+			// Like a Reader, a stream only includes the main body of its channel.
+			if stream == nil || msg.ThreadTimestamp != "" {
+				continue
+			}
+
+			// If the stream is closed while we are writing, this returns an
+			// io.ErrClosedPipe that can be safely ignored.
+			stream.writeIn.Write(append([]byte(msg.Text), '\n'))
+		}
+	}()
+
+	return d, nil
+}
+
+// Channel returns a stream of text from the Slack channel with the given ID,
+// creating it if necessary. Calling Channel again with the same ID returns the
+// same stream until that stream is closed. After the Demux is closed, whether
+// directly or by closing its last open stream, Channel returns streams that are
+// already at EOF.
+func (d *Demux) Channel(channelID string) io.ReadCloser {
+	d.streamsLock.Lock()
+	defer d.streamsLock.Unlock()
+
+	if stream, ok := d.streams[channelID]; ok {
+		return stream
+	}
+
+	stream := &demuxStream{demux: d, channelID: channelID}
+	stream.readOut, stream.writeIn = io.Pipe()
+
+	if d.closed {
+		stream.writeIn.Close()
+		return stream
+	}
+
+	d.streams[channelID] = stream
+	return stream
+}
+
+// Close unsubscribes this Demux from its client, and closes all of its
+// streams. Subsequent calls to Close have no effect.
+func (d *Demux) Close() error {
+	d.closeOnce.Do(func() {
+		if err := d.client.Unsubscribe(d.msgCh); err != nil {
+			// As with Reader, this indicates corruption of the client.
+			panic(err)
+		}
+
+		d.streamsLock.Lock()
+		d.closed = true
+		for _, stream := range d.streams {
+			stream.writeIn.Close()
+		}
+		d.streams = nil
+		d.streamsLock.Unlock()
+
+		close(d.msgCh)
+		d.wg.Wait()
+	})
+
+	return nil
+}
+
+// demuxStream is the stream of text for a single channel within a Demux.
+type demuxStream struct {
+	demux     *Demux
+	channelID string
+	readOut   *io.PipeReader
+	writeIn   *io.PipeWriter
+}
+
+func (s *demuxStream) Read(p []byte) (int, error) {
+	return s.readOut.Read(p)
+}
+
+// Close stops delivery of messages to this stream only. After Close, the next
+// call to Read will result in an EOF. If this was the last open stream in the
+// Demux, the Demux is closed as well.
+func (s *demuxStream) Close() error {
+	d := s.demux
+
+	d.streamsLock.Lock()
+	last := false
+	if d.streams[s.channelID] == s {
+		delete(d.streams, s.channelID)
+		if len(d.streams) == 0 {
+			// Marking the Demux closed right away ensures that a concurrent call to
+			// Channel can't open a new stream that we are about to abandon.
+			d.closed = true
+			last = true
+		}
+	}
+
+	// Closing the write half of the pipe also unblocks any pending write from
+	// the Demux. The call itself always returns nil.
+	s.writeIn.Close()
+	d.streamsLock.Unlock()
+
+	if last {
+		return d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package slackio
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestDemux(t *testing.T) {
+	client := &testReadClient{
+		start: make(chan struct{}),
+		messages: []Message{
+			{Text: "one", ChannelID: "C11111111"},
+			{Text: "two", ChannelID: "C22222222"},
+			{Text: "ignored", ChannelID: "C33333333"},
+			{Text: "three", ChannelID: "C11111111"},
+			{Text: "four", ChannelID: "C22222222"},
+		},
+	}
+
+	d, err := NewDemux(client)
+	if err != nil {
+		t.Fatalf("unexpected NewDemux error: %v", err)
+	}
+
+	r1, r2 := d.Channel("C11111111"), d.Channel("C22222222")
+	if d.Channel("C11111111") != r1 {
+		t.Fatal("Demux returned a new stream for an existing channel")
+	}
+
+	close(client.start)
+
+	// Streams share a subscription, so they must be read concurrently.
+	readExpected := func(r io.Reader, expected string) func() error {
+		return func() error {
+			actual := make([]byte, len(expected))
+			if _, err := io.ReadFull(r, actual); err != nil {
+				return errors.Wrap(err, "unexpected stream error")
+			}
+			if string(actual) != expected {
+				return errors.Errorf("unexpected stream output %q (expected %q)", actual, expected)
+			}
+			return nil
+		}
+	}
+
+	var group errgroup.Group
+	group.Go(readExpected(r1, "one\nthree\n"))
+	group.Go(readExpected(r2, "two\nfour\n"))
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected Demux error: %v", err)
+	}
+
+	var readBytes [16]byte
+	for _, r := range []io.Reader{r1, r2, d.Channel("C33333333")} {
+		if _, err := r.Read(readBytes[:]); err != io.EOF {
+			t.Fatalf("unexpected stream error after Close: %v (expected EOF)", err)
+		}
+	}
+
+	client.wait()
+}
+
+func TestDemuxStreamClose(t *testing.T) {
+	client := &testReadClient{
+		start: make(chan struct{}),
+		messages: []Message{
+			{Text: "one", ChannelID: "C11111111"},
+			{Text: "two", ChannelID: "C22222222"},
+			{Text: "three", ChannelID: "C33333333"},
+			{Text: "four", ChannelID: "C11111111"},
+			{Text: "five", ChannelID: "C22222222"},
+			{Text: "six", ChannelID: "C33333333"},
+		},
+	}
+
+	d, err := NewDemux(client)
+	if err != nil {
+		t.Fatalf("unexpected NewDemux error: %v", err)
+	}
+
+	r1, r2, r3 := d.Channel("C11111111"), d.Channel("C22222222"), d.Channel("C33333333")
+	if err := r1.Close(); err != nil {
+		t.Fatalf("unexpected stream error on close: %v", err)
+	}
+
+	close(client.start)
+
+	readExpected := func(r io.Reader, expected string) func() error {
+		return func() error {
+			actual := make([]byte, len(expected))
+			if _, err := io.ReadFull(r, actual); err != nil {
+				return errors.Wrap(err, "unexpected stream error")
+			}
+			if string(actual) != expected {
+				return errors.Errorf("unexpected stream output %q (expected %q)", actual, expected)
+			}
+			return nil
+		}
+	}
+
+	var group errgroup.Group
+	group.Go(readExpected(r2, "two\nfive\n"))
+	group.Go(readExpected(r3, "three\nsix\n"))
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	var readBytes [16]byte
+	if _, err := r1.Read(readBytes[:]); err != io.EOF {
+		t.Fatalf("unexpected error from closed stream: %v (expected EOF)", err)
+	}
+
+	// The subscription remains until the last stream is closed.
+	r2.Close()
+	if len(client.doneChans) != 1 {
+		t.Fatal("Demux unsubscribed with a stream still open")
+	}
+
+	r3.Close()
+	if len(client.doneChans) != 0 {
+		t.Fatal("Demux remained subscribed after all streams closed")
+	}
+
+	if _, err := d.Channel("C22222222").Read(readBytes[:]); err != io.EOF {
+		t.Fatalf("unexpected stream error after last close: %v (expected EOF)", err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected Demux error: %v", err)
+	}
+	client.wait()
+}
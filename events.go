@@ -0,0 +1,259 @@
+package slackio
+
+import "github.com/nlopes/slack"
+
+// StarEvent describes the addition or removal of a star on a Slack item by
+// the authenticated user.
+type StarEvent struct {
+	// Added is true if the star was added, and false if it was removed.
+	Added bool
+
+	UserID   string
+	ItemType string
+
+	// ChannelID and MessageTimestamp identify the starred message when ItemType
+	// is "message", and are blank otherwise.
+	ChannelID        string
+	MessageTimestamp string
+}
+
+// SubscribeStars causes star events to be sent to the given channel as they
+// are received from Slack. The Client will not block sending to ch; events are
+// dropped if ch is not ready to receive, so callers should use a buffered
+// channel. If the given channel is already subscribed, ErrAlreadySubscribed
+// will be returned.
+func (c *Client) SubscribeStars(ch chan<- StarEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.starSubs[ch]; ok {
+		return ErrAlreadySubscribed
+	}
+
+	c.starSubs[ch] = struct{}{}
+	return nil
+}
+
+// UnsubscribeStars stops the sending of star events to the given channel.
+// After UnsubscribeStars returns, the channel will no longer receive any
+// events and may safely be closed. If the given channel was not previously
+// subscribed, ErrNotSubscribed will be returned.
+func (c *Client) UnsubscribeStars(ch chan<- StarEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.starSubs[ch]; !ok {
+		return ErrNotSubscribed
+	}
+
+	delete(c.starSubs, ch)
+	return nil
+}
+
+// distributeStar sends a star event to all subscribers.
+func (c *Client) distributeStar(added bool, user string, item slack.StarredItem) {
+	evt := StarEvent{
+		Added:    added,
+		UserID:   user,
+		ItemType: item.Type,
+	}
+
+	if item.Message != nil {
+		evt.ChannelID = item.Channel
+		evt.MessageTimestamp = item.Message.Timestamp
+	}
+
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	for ch := range c.starSubs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// ChannelEventKind identifies the type of change described by a ChannelEvent.
+type ChannelEventKind string
+
+const (
+	// ChannelRenamed indicates that a channel was given a new name.
+	ChannelRenamed ChannelEventKind = "rename"
+
+	// ChannelArchived indicates that a channel was archived.
+	ChannelArchived ChannelEventKind = "archive"
+)
+
+// ChannelEvent describes a change to the structure of a Slack channel.
+type ChannelEvent struct {
+	Kind      ChannelEventKind
+	ChannelID string
+
+	// NewName is the channel's name after a ChannelRenamed event, and is blank
+	// otherwise.
+	NewName string
+}
+
+// SubscribeChannelEvents causes channel events to be sent to the given channel
+// as they are received from Slack, following the same rules as SubscribeStars.
+// Events are reported for both public and private channels. If the given
+// channel is already subscribed, ErrAlreadySubscribed will be returned.
+func (c *Client) SubscribeChannelEvents(ch chan<- ChannelEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.channelSubs[ch]; ok {
+		return ErrAlreadySubscribed
+	}
+
+	c.channelSubs[ch] = struct{}{}
+	return nil
+}
+
+// UnsubscribeChannelEvents stops the sending of channel events to the given
+// channel. After UnsubscribeChannelEvents returns, the channel will no longer
+// receive any events and may safely be closed. If the given channel was not
+// previously subscribed, ErrNotSubscribed will be returned.
+func (c *Client) UnsubscribeChannelEvents(ch chan<- ChannelEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.channelSubs[ch]; !ok {
+		return ErrNotSubscribed
+	}
+
+	delete(c.channelSubs, ch)
+	return nil
+}
+
+// distributeChannelEvent sends a channel event to all subscribers.
+func (c *Client) distributeChannelEvent(evt ChannelEvent) {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	for ch := range c.channelSubs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// PinEvent describes the addition or removal of a pinned item in a Slack
+// channel.
+type PinEvent struct {
+	// Added is true if the item was pinned, and false if it was unpinned.
+	Added bool
+
+	UserID    string
+	ChannelID string
+	ItemType  string
+
+	// MessageTimestamp and MessageText identify and describe the pinned message
+	// when ItemType is "message", and are blank otherwise.
+	MessageTimestamp string
+	MessageText      string
+}
+
+// SubscribePins causes pin events to be sent to the given channel as they are
+// received from Slack, following the same rules as SubscribeStars. If the
+// given channel is already subscribed, ErrAlreadySubscribed will be returned.
+func (c *Client) SubscribePins(ch chan<- PinEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.pinSubs[ch]; ok {
+		return ErrAlreadySubscribed
+	}
+
+	c.pinSubs[ch] = struct{}{}
+	return nil
+}
+
+// UnsubscribePins stops the sending of pin events to the given channel. After
+// UnsubscribePins returns, the channel will no longer receive any events and
+// may safely be closed. If the given channel was not previously subscribed,
+// ErrNotSubscribed will be returned.
+func (c *Client) UnsubscribePins(ch chan<- PinEvent) error {
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	if _, ok := c.pinSubs[ch]; !ok {
+		return ErrNotSubscribed
+	}
+
+	delete(c.pinSubs, ch)
+	return nil
+}
+
+// distributePin sends a pin event to all subscribers.
+func (c *Client) distributePin(added bool, user, channelID string, item slack.Item) {
+	evt := PinEvent{
+		Added:     added,
+		UserID:    user,
+		ChannelID: channelID,
+		ItemType:  item.Type,
+	}
+
+	if item.Message != nil {
+		evt.MessageTimestamp = item.Message.Timestamp
+		evt.MessageText = item.Message.Text
+	}
+
+	c.eventSubsLock.Lock()
+	defer c.eventSubsLock.Unlock()
+
+	for ch := range c.pinSubs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// connectionStatesSize is the capacity of the channel returned by
+// ConnectionEvents.
+const connectionStatesSize = 8
+
+// ConnectionState describes the state of a Client's connection to Slack.
+type ConnectionState string
+
+const (
+	// Connecting indicates that the Client is attempting to connect to Slack.
+	Connecting ConnectionState = "connecting"
+
+	// Connected indicates that the Client has connected to Slack.
+	Connected ConnectionState = "connected"
+
+	// Disconnected indicates that the Client has lost its connection to Slack.
+	// Unless the Client is closed, it will attempt to reconnect.
+	Disconnected ConnectionState = "disconnected"
+)
+
+// ConnectionEvents returns a channel that receives the state of this Client's
+// connection to Slack each time that it changes. The channel is buffered, and
+// if the buffer is full, the oldest state is discarded to make room for the
+// newest. The same channel is returned on every call, and is closed when the
+// Client is closed.
+func (c *Client) ConnectionEvents() <-chan ConnectionState {
+	return c.connectionStates
+}
+
+// notifyConnectionState sends a connection state to the ConnectionEvents
+// channel, discarding the oldest state if necessary. It must only be called
+// from the event loop.
+func (c *Client) notifyConnectionState(state ConnectionState) {
+	for {
+		select {
+		case c.connectionStates <- state:
+			return
+		default:
+		}
+
+		select {
+		case <-c.connectionStates:
+		default:
+		}
+	}
+}
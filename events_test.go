@@ -0,0 +1,219 @@
+package slackio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nlopes/slack"
+)
+
+func TestSubscribeStars(t *testing.T) {
+	c := initClient()
+	ch := make(chan StarEvent, 1)
+
+	if err := c.SubscribeStars(ch); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if err := c.SubscribeStars(ch); err != ErrAlreadySubscribed {
+		t.Fatalf("unexpected result on duplicate subscription: %v", err)
+	}
+
+	c.handleEvent(slack.RTMEvent{
+		Type: "star_added",
+		Data: &slack.StarAddedEvent{
+			Type: "star_added",
+			User: "U12345678",
+			Item: slack.StarredItem{
+				Type:    "message",
+				Channel: "C12345678",
+				Message: &slack.Message{Msg: slack.Msg{Timestamp: "1234.5678"}},
+			},
+		},
+	})
+
+	expected := StarEvent{
+		Added:            true,
+		UserID:           "U12345678",
+		ItemType:         "message",
+		ChannelID:        "C12345678",
+		MessageTimestamp: "1234.5678",
+	}
+
+	select {
+	case evt := <-ch:
+		if evt != expected {
+			t.Fatalf("unexpected star event %#v (expected %#v)", evt, expected)
+		}
+	default:
+		t.Fatal("star event was not delivered")
+	}
+
+	if err := c.UnsubscribeStars(ch); err != nil {
+		t.Fatalf("unexpected unsubscribe error: %v", err)
+	}
+	if err := c.UnsubscribeStars(ch); err != ErrNotSubscribed {
+		t.Fatalf("unexpected duplicate unsubscribe result: %v", err)
+	}
+
+	c.handleEvent(slack.RTMEvent{
+		Type: "star_removed",
+		Data: &slack.StarRemovedEvent{Type: "star_removed", User: "U12345678"},
+	})
+
+	select {
+	case evt := <-ch:
+		t.Fatalf("received star event after unsubscribing: %#v", evt)
+	default:
+	}
+}
+
+func TestSubscribeChannelEvents(t *testing.T) {
+	c := initClient()
+	ch := make(chan ChannelEvent, 4)
+
+	if err := c.SubscribeChannelEvents(ch); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if err := c.SubscribeChannelEvents(ch); err != ErrAlreadySubscribed {
+		t.Fatalf("unexpected result on duplicate subscription: %v", err)
+	}
+
+	c.handleEvent(slack.RTMEvent{
+		Type: "channel_rename",
+		Data: &slack.ChannelRenameEvent{
+			Type:    "channel_rename",
+			Channel: slack.ChannelRenameInfo{ID: "C12345678", Name: "new-name"},
+		},
+	})
+	c.handleEvent(slack.RTMEvent{
+		Type: "channel_archive",
+		Data: &slack.ChannelArchiveEvent{Type: "channel_archive", Channel: "C12345678"},
+	})
+
+	// Private channels report the same changes as group events.
+	c.handleEvent(slack.RTMEvent{
+		Type: "group_rename",
+		Data: &slack.GroupRenameEvent{
+			Type:  "group_rename",
+			Group: slack.GroupRenameInfo{ID: "G12345678", Name: "new-secret"},
+		},
+	})
+	c.handleEvent(slack.RTMEvent{
+		Type: "group_archive",
+		Data: &slack.GroupArchiveEvent{Type: "group_archive", Channel: "G12345678"},
+	})
+
+	expected := []ChannelEvent{
+		{Kind: ChannelRenamed, ChannelID: "C12345678", NewName: "new-name"},
+		{Kind: ChannelArchived, ChannelID: "C12345678"},
+		{Kind: ChannelRenamed, ChannelID: "G12345678", NewName: "new-secret"},
+		{Kind: ChannelArchived, ChannelID: "G12345678"},
+	}
+
+	for _, exp := range expected {
+		select {
+		case evt := <-ch:
+			if evt != exp {
+				t.Fatalf("unexpected channel event %#v (expected %#v)", evt, exp)
+			}
+		default:
+			t.Fatalf("channel event %#v was not delivered", exp)
+		}
+	}
+
+	if err := c.UnsubscribeChannelEvents(ch); err != nil {
+		t.Fatalf("unexpected unsubscribe error: %v", err)
+	}
+	if err := c.UnsubscribeChannelEvents(ch); err != ErrNotSubscribed {
+		t.Fatalf("unexpected duplicate unsubscribe result: %v", err)
+	}
+}
+
+func TestSubscribePins(t *testing.T) {
+	c := initClient()
+	ch := make(chan PinEvent, 1)
+
+	if err := c.SubscribePins(ch); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if err := c.SubscribePins(ch); err != ErrAlreadySubscribed {
+		t.Fatalf("unexpected result on duplicate subscription: %v", err)
+	}
+
+	c.handleEvent(slack.RTMEvent{
+		Type: "pin_added",
+		Data: &slack.PinAddedEvent{
+			Type:    "pin_added",
+			User:    "U12345678",
+			Channel: "C12345678",
+			Item: slack.Item{
+				Type:    "message",
+				Channel: "C12345678",
+				Message: &slack.Message{Msg: slack.Msg{Timestamp: "1234.5678", Text: "read this"}},
+			},
+		},
+	})
+
+	expected := PinEvent{
+		Added:            true,
+		UserID:           "U12345678",
+		ChannelID:        "C12345678",
+		ItemType:         "message",
+		MessageTimestamp: "1234.5678",
+		MessageText:      "read this",
+	}
+
+	select {
+	case evt := <-ch:
+		if evt != expected {
+			t.Fatalf("unexpected pin event %#v (expected %#v)", evt, expected)
+		}
+	default:
+		t.Fatal("pin event was not delivered")
+	}
+
+	if err := c.UnsubscribePins(ch); err != nil {
+		t.Fatalf("unexpected unsubscribe error: %v", err)
+	}
+	if err := c.UnsubscribePins(ch); err != ErrNotSubscribed {
+		t.Fatalf("unexpected duplicate unsubscribe result: %v", err)
+	}
+}
+
+func TestConnectionEvents(t *testing.T) {
+	c := initClient()
+
+	c.handleEvent(slack.RTMEvent{Type: "connecting", Data: &slack.ConnectingEvent{Attempt: 1}})
+	c.handleEvent(slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}})
+	c.handleEvent(slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{}})
+	c.Close()
+
+	var states []ConnectionState
+	for state := range c.ConnectionEvents() {
+		states = append(states, state)
+	}
+
+	expected := []ConnectionState{Connecting, Connected, Disconnected}
+	if !reflect.DeepEqual(states, expected) {
+		t.Fatalf("unexpected connection states %v (expected %v)", states, expected)
+	}
+}
+
+func TestConnectionEventsOverflow(t *testing.T) {
+	c := initClient()
+
+	for i := 0; i < connectionStatesSize; i++ {
+		c.handleEvent(slack.RTMEvent{Type: "connecting", Data: &slack.ConnectingEvent{Attempt: i + 1}})
+	}
+	c.handleEvent(slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}})
+	c.Close()
+
+	var states []ConnectionState
+	for state := range c.ConnectionEvents() {
+		states = append(states, state)
+	}
+
+	if len(states) != connectionStatesSize || states[len(states)-1] != Connected {
+		t.Fatalf("newest state was not retained on overflow: %v", states)
+	}
+}
@@ -0,0 +1,35 @@
+package slackio
+
+import (
+	"expvar"
+	"sync"
+)
+
+var (
+	expvarMap     *expvar.Map
+	expvarMapOnce sync.Once
+)
+
+// publishExpvar publishes this Client's internal state in the "slackio" expvar
+// map, replacing any values previously published by another Client.
+func (c *Client) publishExpvar() {
+	expvarMapOnce.Do(func() {
+		expvarMap = expvar.NewMap("slackio")
+	})
+
+	expvarMap.Set("queue_length", expvar.Func(func() interface{} {
+		c.messagesLock.RLock()
+		defer c.messagesLock.RUnlock()
+		return len(c.messages)
+	}))
+
+	expvarMap.Set("next_message_id", expvar.Func(func() interface{} {
+		c.messagesLock.RLock()
+		defer c.messagesLock.RUnlock()
+		return c.nextMessageID
+	}))
+
+	expvarMap.Set("subscription_count", expvar.Func(func() interface{} {
+		return c.SubscriptionCount()
+	}))
+}
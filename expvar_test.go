@@ -0,0 +1,49 @@
+package slackio
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/nlopes/slack"
+)
+
+func TestEnableExpvar(t *testing.T) {
+	c := initClient(ClientOptionEnableExpvar())
+	defer c.Close()
+
+	vars, ok := expvar.Get("slackio").(*expvar.Map)
+	if !ok {
+		t.Fatal("slackio expvar map was not published")
+	}
+
+	expectVars := func(expected map[string]string) {
+		t.Helper()
+		for name, value := range expected {
+			if v := vars.Get(name); v == nil || v.String() != value {
+				t.Errorf("unexpected value %v for %s (expected %s)", v, name, value)
+			}
+		}
+	}
+
+	expectVars(map[string]string{
+		"queue_length":       "0",
+		"next_message_id":    "0",
+		"subscription_count": "0",
+	})
+
+	if err := c.Subscribe(make(chan Message, messageQueueSize)); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+
+	msg := slack.Msg{Type: "message", Channel: "C12345678", Text: "hi"}
+	evt := slack.MessageEvent(slack.Message{Msg: msg})
+	for i := 0; i < 3; i++ {
+		c.distribute(&evt)
+	}
+
+	expectVars(map[string]string{
+		"queue_length":       "3",
+		"next_message_id":    "3",
+		"subscription_count": "1",
+	})
+}
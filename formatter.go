@@ -0,0 +1,35 @@
+package slackio
+
+import "encoding/json"
+
+// MessageFormatter converts Messages into the bytes that represent them in the
+// output of a Reader. See ReaderOptionFormatter.
+type MessageFormatter interface {
+	Format(Message) []byte
+}
+
+// PlainFormatter is a MessageFormatter that outputs the text of each message
+// followed by a newline, as a Reader does by default.
+type PlainFormatter struct{}
+
+// Format implements MessageFormatter.
+func (PlainFormatter) Format(msg Message) []byte {
+	return append([]byte(msg.Text), '\n')
+}
+
+// JSONFormatter is a MessageFormatter that outputs each message as a single
+// line of JSON, as encoded by encoding/json. This allows consumers to access
+// fields other than the message text, such as ChannelID and UserID.
+type JSONFormatter struct{}
+
+// Format implements MessageFormatter.
+func (JSONFormatter) Format(msg Message) []byte {
+	out, err := json.Marshal(msg)
+	if err != nil {
+		// A Message can only fail to encode if it carries an invalid value in a
+		// field like Metadata. Fall back to a message that can be encoded.
+		out, _ = json.Marshal(Message{ID: msg.ID, ChannelID: msg.ChannelID, UserID: msg.UserID, Text: msg.Text})
+	}
+
+	return append(out, '\n')
+}
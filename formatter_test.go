@@ -0,0 +1,57 @@
+package slackio
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestPlainFormatter(t *testing.T) {
+	out := PlainFormatter{}.Format(Message{ChannelID: "C12345678", UserID: "U12345678", Text: "hello"})
+	if string(out) != "hello\n" {
+		t.Fatalf("unexpected output %q (expected %q)", out, "hello\n")
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	msg := Message{ID: 3, ChannelID: "C12345678", UserID: "U12345678", Text: "line one\nline two"}
+	out := JSONFormatter{}.Format(msg)
+
+	if len(out) == 0 || out[len(out)-1] != '\n' {
+		t.Fatalf("output %q is not newline-terminated", out)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", out, err)
+	}
+
+	if decoded.ID != msg.ID || decoded.ChannelID != msg.ChannelID || decoded.UserID != msg.UserID || decoded.Text != msg.Text {
+		t.Fatalf("unexpected decoded message %#v (expected %#v)", decoded, msg)
+	}
+}
+
+func TestReaderFormatter(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "one\r\ntwo", ChannelID: "C12345678", UserID: "U12345678"},
+		},
+	}
+
+	r := NewReader(client, "", ReaderOptionFormatter(JSONFormatter{}), ReaderOptionTranscript())
+
+	expected := `{"ID":0,"ChannelID":"C12345678","UserID":"U12345678","Text":"one\ntwo",`
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected prefix %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+}
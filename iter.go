@@ -0,0 +1,54 @@
+//go:build go1.23
+
+package slackio
+
+import (
+	"context"
+	"iter"
+)
+
+// Messages returns an iterator over the messages in this Client's stream,
+// starting immediately after the latest message, for use in a range loop:
+//
+//	for msg, err := range client.Messages(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		// Handle msg.
+//	}
+//
+// Each iteration of the loop subscribes to the Client anew, and the
+// subscription ends when the loop does. If ctx is done, the iterator yields
+// ctx.Err() and stops; if the Client is closed, it yields ErrClientClosed and
+// stops. As with SubscribeAt, a loop that falls behind the Client's message
+// buffer is skipped forward.
+func (c *Client) Messages(ctx context.Context) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		ch := make(chan Message)
+		if err := c.Subscribe(ch); err != nil {
+			yield(Message{}, err)
+			return
+		}
+
+		// An error means that Close already ended the subscription, as it
+		// forgets every subscription that it stops.
+		defer c.Unsubscribe(ch)
+
+		for {
+			select {
+			case msg := <-ch:
+				if !yield(msg, nil) {
+					return
+				}
+
+			case <-ctx.Done():
+				yield(Message{}, ctx.Err())
+				return
+
+			case <-c.done:
+				yield(Message{}, ErrClientClosed)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+//go:build go1.23
+
+package slackio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+func TestClientMessages(t *testing.T) {
+	c := initClient()
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		// Wait for the loop to subscribe so that no messages are missed.
+		for c.SubscriptionCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		for _, text := range []string{"one", "two", "three", "four"} {
+			msg := slack.Msg{Type: "message", Channel: "C12345678", Text: text}
+			evt := slack.MessageEvent(slack.Message{Msg: msg})
+			c.distribute(&evt)
+		}
+	}()
+
+	var texts []string
+	for msg, err := range c.Messages(ctx) {
+		if err != nil {
+			t.Fatalf("unexpected iterator error: %v", err)
+		}
+
+		texts = append(texts, msg.Text)
+		if len(texts) == 3 {
+			break
+		}
+	}
+
+	if len(texts) != 3 || texts[0] != "one" || texts[2] != "three" {
+		t.Fatalf("unexpected messages %q", texts)
+	}
+
+	if count := c.SubscriptionCount(); count != 0 {
+		t.Fatalf("unexpected subscription count %d after breaking from loop", count)
+	}
+
+	// A canceled context ends the loop with its error.
+	cancel()
+	for _, err := range c.Messages(ctx) {
+		if err != context.Canceled {
+			t.Fatalf("unexpected iterator error %v (expected context.Canceled)", err)
+		}
+	}
+
+	if count := c.SubscriptionCount(); count != 0 {
+		t.Fatalf("unexpected subscription count %d after cancellation", count)
+	}
+}
+
+func TestClientMessagesClose(t *testing.T) {
+	c := initClient()
+
+	go func() {
+		for c.SubscriptionCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		c.Close()
+	}()
+
+	// Closing the Client ends the loop normally, without a panic as the
+	// iterator cleans up its subscription.
+	var errs []error
+	for _, err := range c.Messages(context.Background()) {
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 1 || errs[0] != ErrClientClosed {
+		t.Fatalf("unexpected iterator errors %v (expected [%v])", errs, ErrClientClosed)
+	}
+}
@@ -0,0 +1,110 @@
+package slackio
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	markdownCode    = regexp.MustCompile("```[\\s\\S]*?```|`[^`\\n]*`")
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+?)[ \t#]*$`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownBold    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownItalic  = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	markdownStrike  = regexp.MustCompile(`~~(.+?)~~`)
+)
+
+// ConvertMarkdown converts common Markdown constructs in s to their
+// equivalents in Slack's mrkdwn dialect. Bold, italic, and strikethrough text
+// are converted, as are inline links. Headings, which mrkdwn does not support,
+// are converted to bold text. Code spans and fenced code blocks are left as-is,
+// along with any constructs that are not recognized. Double underscores only
+// mark bold text where they stand apart from the surrounding text, so that
+// identifiers like __init__() are left alone.
+func ConvertMarkdown(s string) string {
+	var out strings.Builder
+
+	for {
+		loc := markdownCode.FindStringIndex(s)
+		if loc == nil {
+			out.WriteString(convertMarkdownText(s))
+			return out.String()
+		}
+
+		out.WriteString(convertMarkdownText(s[:loc[0]]))
+		out.WriteString(s[loc[0]:loc[1]])
+		s = s[loc[1]:]
+	}
+}
+
+// convertMarkdownText converts Markdown text that does not contain any code.
+func convertMarkdownText(s string) string {
+	s = markdownLink.ReplaceAllString(s, "<$2|$1>")
+	s = markdownStrike.ReplaceAllString(s, "~$1~")
+
+	// Text that becomes bold is converted separately from its surroundings, so
+	// that the resulting asterisks are never mistaken for Markdown italics.
+	var out strings.Builder
+	last := 0
+	for _, m := range markdownHeading.FindAllStringSubmatchIndex(s, -1) {
+		out.WriteString(convertEmphasis(s[last:m[0]]))
+		out.WriteString("*" + convertEmphasis(s[m[2]:m[3]]) + "*")
+		last = m[1]
+	}
+	out.WriteString(convertEmphasis(s[last:]))
+
+	return out.String()
+}
+
+// convertEmphasis converts Markdown bold and italic text in s.
+func convertEmphasis(s string) string {
+	var out strings.Builder
+	last := 0
+
+	for pos := 0; pos < len(s); {
+		m := markdownBold.FindStringSubmatchIndex(s[pos:])
+		if m == nil {
+			break
+		}
+		for i := range m {
+			if m[i] >= 0 {
+				m[i] += pos
+			}
+		}
+
+		content := m[2:4]
+		if m[2] < 0 {
+			if !isUnderscoreDelimited(s, m[0], m[1]) {
+				pos = m[0] + len("__")
+				continue
+			}
+			content = m[4:6]
+		}
+
+		out.WriteString(convertItalic(s[last:m[0]]))
+		out.WriteString("*" + convertItalic(s[content[0]:content[1]]) + "*")
+		last, pos = m[1], m[1]
+	}
+
+	out.WriteString(convertItalic(s[last:]))
+	return out.String()
+}
+
+// convertItalic converts Markdown italic text in s.
+func convertItalic(s string) string {
+	return markdownItalic.ReplaceAllString(s, "_${1}_")
+}
+
+// isUnderscoreDelimited reports whether the underscores around s[start:end]
+// stand apart from the surrounding text, such that they mark emphasis rather
+// than being part of a word or identifier like snake__case or obj.__init__().
+func isUnderscoreDelimited(s string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:start])
+	after, _ := utf8.DecodeRuneInString(s[end:])
+
+	opens := start == 0 || unicode.IsSpace(before) || strings.ContainsRune(`([{"'`, before)
+	closes := end == len(s) || unicode.IsSpace(after) || strings.ContainsRune(`)]}"'.,;:!?`, after)
+	return opens && closes
+}
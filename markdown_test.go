@@ -0,0 +1,85 @@
+package slackio
+
+import "testing"
+
+func TestConvertMarkdown(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		output      string
+	}{
+		{
+			"converts bold text",
+			"this is **important** and __urgent__",
+			"this is *important* and *urgent*",
+		},
+		{
+			"converts italic text",
+			"this is *emphasized* and _also emphasized_",
+			"this is _emphasized_ and _also emphasized_",
+		},
+		{
+			"converts nested emphasis",
+			"**bold with *italic* inside**",
+			"*bold with _italic_ inside*",
+		},
+		{
+			"converts strikethrough text",
+			"this is ~~wrong~~",
+			"this is ~wrong~",
+		},
+		{
+			"converts links",
+			"see [the docs](https://example.com/docs) for details",
+			"see <https://example.com/docs|the docs> for details",
+		},
+		{
+			"converts headings to bold text",
+			"# Title\nbody text\n## Section ##",
+			"*Title*\nbody text\n*Section*",
+		},
+		{
+			"leaves code spans alone",
+			"run `**not bold**` now",
+			"run `**not bold**` now",
+		},
+		{
+			"leaves code blocks alone",
+			"```\n# not a heading\n*not italic*\n```\n**bold**",
+			"```\n# not a heading\n*not italic*\n```\n*bold*",
+		},
+		{
+			"leaves underscores within identifiers alone",
+			"call obj.__init__() or __init__() on snake__case__name",
+			"call obj.__init__() or __init__() on snake__case__name",
+		},
+		{
+			"converts underscore bold text beside punctuation",
+			"(__first__), __second__.",
+			"(*first*), *second*.",
+		},
+		{
+			"leaves NUL characters alone",
+			"a\x00b **bold** c\x00",
+			"a\x00b *bold* c\x00",
+		},
+		{
+			"converts emphasis within headings",
+			"# Title with *emphasis*",
+			"*Title with _emphasis_*",
+		},
+		{
+			"leaves unrecognized constructs alone",
+			"2 * 3 * 4 > #hashtag",
+			"2 * 3 * 4 > #hashtag",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			if out := ConvertMarkdown(tc.input); out != tc.output {
+				t.Fatalf("unexpected conversion %q (expected %q)", out, tc.output)
+			}
+		})
+	}
+}
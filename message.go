@@ -1,9 +1,166 @@
 package slackio
 
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
 // Message is the type for messages received from and sent to a single Slack
 // channel.
 type Message struct {
 	ID        int
 	ChannelID string
+	UserID    string
 	Text      string
+
+	// Timestamp is the timestamp that Slack assigned to an incoming message,
+	// which uniquely identifies it within its channel. It may be used as the
+	// ThreadTimestamp of an outgoing Message to reply in the message's thread.
+	Timestamp string
+
+	// ClientMsgID is a stable identifier for an incoming message, which is
+	// unique across all channels and may be used to deduplicate messages that
+	// are processed more than once, such as across restarts. Slack assigns a
+	// client_msg_id to messages sent by users, but the version of the slack
+	// package that slackio uses does not decode it, so ClientMsgID is currently
+	// always derived from ChannelID and Timestamp. It is blank for edits (see
+	// ClientOptionEdits), which share the Timestamp of the original message.
+	ClientMsgID string
+
+	// ChannelType is the type of conversation that an incoming message was sent
+	// in, as inferred from its ChannelID by ChannelTypeFromID.
+	ChannelType ChannelType
+
+	// TeamID identifies the team that a message was sent from. In Enterprise
+	// Grid organizations, it disambiguates channels that are shared between
+	// teams. It is blank for messages from workspaces outside of Enterprise Grid.
+	TeamID string
+
+	// Permalink is a URL that links directly to an incoming message, which is
+	// only populated for a Client using ClientOptionResolvePermalinks.
+	Permalink string
+
+	// Reactions maps the name of each emoji reaction on an incoming message to
+	// the number of users who reacted with it, as of the time the Message was
+	// received. It is nil if the message had no reactions. Each subscriber
+	// receives its own copy of the map, which it may modify freely.
+	//
+	// Note that because Reactions is a map, Messages cannot be compared with ==.
+	Reactions map[string]int
+
+	// Blocks holds the Block Kit blocks that make up an incoming message, for
+	// programs that process its structured content rather than its Text. It is
+	// nil if the message has no blocks.
+	Blocks []slack.Block
+
+	// Edited is true if the Message represents an edit to an earlier message,
+	// which is only possible for a Client using ClientOptionEdits. For edits,
+	// Text and NewText both hold the new text of the message, while OldText holds
+	// its text prior to the edit, if known.
+	Edited  bool
+	OldText string
+	NewText string
+
+	// ThreadTimestamp, if non-blank, causes an outgoing Message to be sent as a
+	// reply within the thread that it identifies. For incoming Messages, it
+	// identifies the thread that a reply belongs to, which is only possible for
+	// a Client using ClientOptionTrackReplies.
+	ThreadTimestamp string
+
+	// Metadata, if non-nil, is attached to an outgoing Message sent with
+	// PostMessage. It is not set for incoming Messages, as the version of the
+	// slack package that slackio uses does not decode message metadata.
+	Metadata *Metadata
+
+	// Username and IconEmoji, if non-blank, override the identity under which an
+	// outgoing Message sent with PostMessage appears.
+	Username  string
+	IconEmoji string
+
+	// BroadcastReply, if true, causes an outgoing Message sent with PostMessage
+	// as a reply within a thread to also appear in the main body of its
+	// channel.
+	BroadcastReply bool
+
+	// Priority determines the order in which outgoing Messages are delivered
+	// when a Client using ClientOptionSendInterval has queued several of them.
+	// Higher priorities are delivered first. The default of 0 is intended for
+	// bulk output, with positive values reserved for urgent messages.
+	Priority int
+
+	// DisableMrkdwn, if true, causes the text of an outgoing Message sent with
+	// PostMessage to be displayed literally, without Slack's mrkdwn formatting.
+	DisableMrkdwn bool
+}
+
+// Metadata is structured event metadata attached to a Slack message. It allows
+// applications to exchange machine-readable data alongside human-readable
+// message text.
+type Metadata struct {
+	EventType    string                 `json:"event_type"`
+	EventPayload map[string]interface{} `json:"event_payload"`
+}
+
+// ChannelType identifies a type of Slack conversation.
+type ChannelType string
+
+const (
+	// ChannelTypeUnknown indicates a conversation of an unrecognized type.
+	ChannelTypeUnknown ChannelType = ""
+
+	// ChannelTypePublic indicates a public channel.
+	ChannelTypePublic ChannelType = "channel"
+
+	// ChannelTypePrivate indicates a private channel. Multi-party direct
+	// messages share the same ID prefix as private channels, and so are
+	// reported with this type as well.
+	ChannelTypePrivate ChannelType = "group"
+
+	// ChannelTypeDM indicates a direct message between two users.
+	ChannelTypeDM ChannelType = "im"
+)
+
+// ChannelTypeFromID infers the type of a Slack conversation from the prefix of
+// its ID. Note that Slack assigns some newer private channels IDs with the same
+// prefix as public channels, so the result should be treated as a hint rather
+// than a guarantee of the conversation's visibility.
+func ChannelTypeFromID(id string) ChannelType {
+	if id == "" {
+		return ChannelTypeUnknown
+	}
+
+	switch id[0] {
+	case 'C':
+		return ChannelTypePublic
+	case 'G':
+		return ChannelTypePrivate
+	case 'D':
+		return ChannelTypeDM
+	default:
+		return ChannelTypeUnknown
+	}
+}
+
+// Time returns the time at which an incoming message was sent, as encoded in
+// its Timestamp, with microsecond precision. It returns false if the Message
+// has no valid Timestamp.
+func (m Message) Time() (time.Time, bool) {
+	parts := strings.SplitN(m.Timestamp, ".", 2)
+
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var usec int64
+	if len(parts) > 1 {
+		if usec, err = strconv.ParseInt(parts[1], 10, 64); err != nil || len(parts[1]) != 6 {
+			return time.Time{}, false
+		}
+	}
+
+	return time.Unix(sec, usec*int64(time.Microsecond)), true
 }
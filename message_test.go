@@ -0,0 +1,22 @@
+package slackio
+
+import "testing"
+
+func TestChannelTypeFromID(t *testing.T) {
+	cases := []struct {
+		id       string
+		expected ChannelType
+	}{
+		{"C12345678", ChannelTypePublic},
+		{"G12345678", ChannelTypePrivate},
+		{"D12345678", ChannelTypeDM},
+		{"U12345678", ChannelTypeUnknown},
+		{"", ChannelTypeUnknown},
+	}
+
+	for _, tc := range cases {
+		if actual := ChannelTypeFromID(tc.id); actual != tc.expected {
+			t.Errorf("unexpected channel type %q for %q (expected %q)", actual, tc.id, tc.expected)
+		}
+	}
+}
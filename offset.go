@@ -0,0 +1,59 @@
+package slackio
+
+import "sync"
+
+// OffsetStore represents durable storage for the ID of the latest Message that
+// a consumer has processed, which allows the consumer to resume from that
+// position later (see SubscribeWithOffsets).
+type OffsetStore interface {
+	// Commit records id as the ID of the latest processed Message.
+	Commit(id int)
+
+	// Load returns the most recently committed ID, and true if one exists.
+	Load() (int, bool)
+}
+
+// MemoryOffsetStore is an OffsetStore that holds its offset in memory. The
+// zero value is an empty store that is ready to use.
+type MemoryOffsetStore struct {
+	mu        sync.Mutex
+	id        int
+	committed bool
+}
+
+// Commit implements OffsetStore.
+func (s *MemoryOffsetStore) Commit(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id, s.committed = id, true
+}
+
+// Load implements OffsetStore.
+func (s *MemoryOffsetStore) Load() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id, s.committed
+}
+
+// SubscribeWithOffsets creates a new subscription within this Client that
+// invokes fn for each message, exactly as SubscribeCallback does, and commits
+// each message's ID to store after fn returns. The subscription begins
+// immediately after the offset loaded from store, or after the latest message
+// in the stream if store has no offset. Since a message is only committed once
+// it has been processed, a consumer that stops partway through fn will see
+// the same message again when it resumes.
+//
+// Note that message IDs are only meaningful within a single Client, so an
+// offset can only be resumed from while that Client's stream retains the
+// messages following it.
+func (c *Client) SubscribeWithOffsets(store OffsetStore, fn func(Message)) (cancel func()) {
+	id := -1
+	if offset, ok := store.Load(); ok {
+		id = offset + 1
+	}
+
+	return c.SubscribeCallback(id, func(msg Message) {
+		fn(msg)
+		store.Commit(msg.ID)
+	})
+}
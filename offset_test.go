@@ -0,0 +1,63 @@
+package slackio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nlopes/slack"
+)
+
+func TestSubscribeWithOffsets(t *testing.T) {
+	c := initClient()
+	defer c.Close()
+
+	distribute := func(texts ...string) {
+		for _, text := range texts {
+			msg := slack.Msg{Type: "message", Channel: "C12345678", Text: text}
+			evt := slack.MessageEvent(slack.Message{Msg: msg})
+			c.distribute(&evt)
+		}
+	}
+
+	store := &MemoryOffsetStore{}
+	if _, ok := store.Load(); ok {
+		t.Fatal("new store unexpectedly has an offset")
+	}
+
+	received := make(chan string)
+	consume := func(m Message) { received <- m.Text }
+
+	expectReceived := func(expected ...string) {
+		t.Helper()
+
+		var actual []string
+		for range expected {
+			actual = append(actual, <-received)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("unexpected messages %q (expected %q)", actual, expected)
+		}
+	}
+
+	// With no offset, the subscription begins with new messages.
+	distribute("zero")
+	cancel := c.SubscribeWithOffsets(store, consume)
+	distribute("one", "two")
+	expectReceived("one", "two")
+	cancel()
+
+	if id, ok := store.Load(); !ok || id != 2 {
+		t.Fatalf("unexpected offset %d, %v (expected 2, true)", id, ok)
+	}
+
+	// Messages that arrive while no consumer is running are delivered when it
+	// resumes, and none that were committed are delivered again.
+	distribute("three", "four")
+	cancel = c.SubscribeWithOffsets(store, consume)
+	expectReceived("three", "four")
+	cancel()
+
+	if id, ok := store.Load(); !ok || id != 4 {
+		t.Fatalf("unexpected offset %d, %v (expected 4, true)", id, ok)
+	}
+}
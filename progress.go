@@ -0,0 +1,80 @@
+package slackio
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nlopes/slack"
+)
+
+// ErrProgressDone is returned by the methods of a ProgressMessage after Done has
+// been called.
+var ErrProgressDone = errors.New("slackio: progress message is already done")
+
+// ProgressMessage is a single Slack message that is edited in place to report
+// the progress of a long-running task, such as a deployment. See
+// Client.StartProgress.
+type ProgressMessage struct {
+	client    *Client
+	channelID string
+	ts        string
+
+	lock sync.Mutex
+	done bool
+}
+
+// StartProgress sends a message with the given initial text to a Slack channel
+// using Slack's Web API, and returns a ProgressMessage that edits it in place.
+func (c *Client) StartProgress(channelID, initial string) (*ProgressMessage, error) {
+	ts, err := c.postMessage(context.Background(), Message{ChannelID: channelID, Text: initial})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProgressMessage{client: c, channelID: channelID, ts: ts}, nil
+}
+
+// Update replaces the text of the progress message.
+func (p *ProgressMessage) Update(text string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.done {
+		return ErrProgressDone
+	}
+
+	return p.update(text)
+}
+
+// Done replaces the text of the progress message for the last time. After
+// Done returns, whether or not the edit succeeded, further calls to Update or
+// Done return ErrProgressDone.
+func (p *ProgressMessage) Done(final string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.done {
+		return ErrProgressDone
+	}
+
+	p.done = true
+	return p.update(final)
+}
+
+// update edits the progress message. It must be called with lock held.
+func (p *ProgressMessage) update(text string) error {
+	c := p.client
+	if err := c.checkSend(p.channelID); err != nil {
+		return err
+	}
+
+	// A blank timestamp means that the initial message was a dry run.
+	if c.dryRun || p.ts == "" {
+		c.logDryRun(p.channelID, text)
+		return nil
+	}
+
+	_, _, _, err := c.webAPIClient().UpdateMessage(p.channelID, p.ts, slack.MsgOptionText(text, false))
+	return err
+}
@@ -0,0 +1,52 @@
+package slackio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgressMessage(t *testing.T) {
+	api := &testWebAPI{}
+	c := initClient()
+	c.api = api
+
+	p, err := c.StartProgress("C12345678", "deploying: 0%")
+	if err != nil {
+		t.Fatalf("unexpected StartProgress error: %v", err)
+	}
+
+	if len(api.posts) != 1 || api.posts[0].values.Get("text") != "deploying: 0%" {
+		t.Fatalf("unexpected posts %#v", api.posts)
+	}
+
+	for _, text := range []string{"deploying: 50%", "deploying: 100%"} {
+		if err := p.Update(text); err != nil {
+			t.Fatalf("unexpected Update error: %v", err)
+		}
+	}
+
+	if err := p.Done("deployed"); err != nil {
+		t.Fatalf("unexpected Done error: %v", err)
+	}
+
+	if err := p.Update("too late"); err != ErrProgressDone {
+		t.Fatalf("unexpected Update error after Done: %v (expected ErrProgressDone)", err)
+	}
+
+	if len(api.posts) != 1 {
+		t.Fatalf("unexpected post count %d (expected 1)", len(api.posts))
+	}
+
+	var texts []string
+	for _, update := range api.updates {
+		if update.channelID != "C12345678" || update.ts != "1234.0001" {
+			t.Errorf("update sent to %s at %s (expected C12345678 at 1234.0001)", update.channelID, update.ts)
+		}
+		texts = append(texts, update.values.Get("text"))
+	}
+
+	expected := []string{"deploying: 50%", "deploying: 100%", "deployed"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected update texts %q (expected %q)", texts, expected)
+	}
+}
@@ -0,0 +1,134 @@
+package slackio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// ClientOptionWebAPIRate causes a Client to make at most perSecond calls per
+// second to Slack's Web API, across every method that uses it (e.g.
+// PostMessage, SendTemporary, and UploadSnippet), so that bursts of activity
+// stay within Slack's rate limits. Calls in excess of the rate wait for their
+// turn, in the order they were made. The permalink lookups made for
+// ClientOptionResolvePermalinks are exempt, as waiting for them would hold up
+// the Client's processing of incoming events. perSecond must be positive, or
+// ClientOptionWebAPIRate will panic.
+func ClientOptionWebAPIRate(perSecond float64) ClientOption {
+	if perSecond <= 0 {
+		panic(errors.New("slackio: ClientOptionWebAPIRate requires a positive rate"))
+	}
+
+	return func(c *Client) {
+		c.apiLimiter = &rateLimiter{
+			interval: time.Duration(float64(time.Second) / perSecond),
+			clock:    defaultClock{},
+		}
+	}
+}
+
+// rateLimiter paces a series of operations to occur no more often than once per
+// interval.
+type rateLimiter struct {
+	interval time.Duration
+	clock    Clock
+
+	lock sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the caller may perform an operation, or until ctx is done.
+// Each call reserves the next available slot, even if ctx is done before the
+// slot arrives.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.lock.Lock()
+	now := l.clock.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.lock.Unlock()
+
+	if !slot.After(now) {
+		return nil
+	}
+
+	select {
+	case <-l.clock.After(slot.Sub(now)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// webAPIClient returns the webAPI through which this Client calls Slack's Web
+// API, subject to the Client's rate limit if it has one.
+func (c *Client) webAPIClient() webAPI {
+	if c.apiLimiter == nil {
+		return c.api
+	}
+	return limitedWebAPI{c.api, c.apiLimiter}
+}
+
+// limitedWebAPI is a webAPI that waits on a rateLimiter before each call.
+type limitedWebAPI struct {
+	api     webAPI
+	limiter *rateLimiter
+}
+
+func (a limitedWebAPI) DeleteMessage(channelID, ts string) (string, string, error) {
+	a.limiter.wait(context.Background())
+	return a.api.DeleteMessage(channelID, ts)
+}
+
+func (a limitedWebAPI) GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error) {
+	a.limiter.wait(context.Background())
+	return a.api.GetConversationInfo(channelID, includeLocale)
+}
+
+// GetPermalink is exempt from the rate limit, as the Client calls it from its
+// event loop (see ClientOptionResolvePermalinks).
+func (a limitedWebAPI) GetPermalink(p *slack.PermalinkParameters) (string, error) {
+	return a.api.GetPermalink(p)
+}
+
+func (a limitedWebAPI) GetUserByEmail(email string) (*slack.User, error) {
+	a.limiter.wait(context.Background())
+	return a.api.GetUserByEmail(email)
+}
+
+func (a limitedWebAPI) JoinConversation(channelID string) (*slack.Channel, string, []string, error) {
+	a.limiter.wait(context.Background())
+	return a.api.JoinConversation(channelID)
+}
+
+func (a limitedWebAPI) OpenConversation(p *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	a.limiter.wait(context.Background())
+	return a.api.OpenConversation(p)
+}
+
+func (a limitedWebAPI) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	if err := a.limiter.wait(ctx); err != nil {
+		return "", "", err
+	}
+	return a.api.PostMessageContext(ctx, channelID, options...)
+}
+
+func (a limitedWebAPI) UnArchiveConversation(channelID string) error {
+	a.limiter.wait(context.Background())
+	return a.api.UnArchiveConversation(channelID)
+}
+
+func (a limitedWebAPI) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	a.limiter.wait(context.Background())
+	return a.api.UpdateMessage(channelID, timestamp, options...)
+}
+
+func (a limitedWebAPI) UploadFile(p slack.FileUploadParameters) (*slack.File, error) {
+	a.limiter.wait(context.Background())
+	return a.api.UploadFile(p)
+}
@@ -0,0 +1,79 @@
+package slackio
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// stoppedClock is a Clock that stands still, and whose timers fire immediately
+// after recording the duration they were started with.
+type stoppedClock struct {
+	now time.Time
+
+	waitsLock sync.Mutex
+	waits     []time.Duration
+}
+
+func (c *stoppedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *stoppedClock) After(d time.Duration) <-chan time.Time {
+	c.waitsLock.Lock()
+	defer c.waitsLock.Unlock()
+	c.waits = append(c.waits, d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+	return ch
+}
+
+func TestWebAPIRate(t *testing.T) {
+	// The clock stands still, so every wait is a measure of how far ahead the
+	// limiter has scheduled the call.
+	clock := &stoppedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	api := &testWebAPI{}
+	c := initClient(ClientOptionWebAPIRate(2), ClientOptionResolvePermalinks())
+	c.api = api
+	c.apiLimiter.clock = clock
+
+	// Different kinds of calls share the same limit.
+	if _, err := c.PostMessage(Message{ChannelID: "C12345678", Text: "one"}); err != nil {
+		t.Fatalf("unexpected PostMessage error: %v", err)
+	}
+	if err := c.JoinChannel("C12345678"); err != nil {
+		t.Fatalf("unexpected JoinChannel error: %v", err)
+	}
+	if err := c.UploadSnippet("C12345678", "snippet", ""); err != nil {
+		t.Fatalf("unexpected UploadSnippet error: %v", err)
+	}
+	if _, err := c.PostMessage(Message{ChannelID: "C12345678", Text: "two"}); err != nil {
+		t.Fatalf("unexpected PostMessage error: %v", err)
+	}
+
+	// Permalink lookups from the event loop do not wait for the limit.
+	evt := slack.MessageEvent(slack.Message{Msg: slack.Msg{
+		Type:      "message",
+		Channel:   "C12345678",
+		Text:      "hi",
+		Timestamp: "1234.5678",
+	}})
+	c.distribute(&evt)
+	if c.messages[0].Permalink == "" {
+		t.Fatal("distributed message without a permalink")
+	}
+
+	expected := []time.Duration{500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	if !reflect.DeepEqual(clock.waits, expected) {
+		t.Fatalf("unexpected waits %v (expected %v)", clock.waits, expected)
+	}
+
+	if len(api.posts) != 2 || len(api.joins) != 1 || len(api.uploads) != 1 {
+		t.Fatalf("not all calls were made: %d posts, %d joins, %d uploads", len(api.posts), len(api.joins), len(api.uploads))
+	}
+}
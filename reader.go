@@ -1,8 +1,11 @@
 package slackio
 
 import (
+	"errors"
 	"io"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ReadClient represents objects that allow subscription to a stream of slackio
@@ -12,28 +15,383 @@ type ReadClient interface {
 	Unsubscribe(chan<- Message) error
 }
 
+// ErrDeadlineExceeded is returned by Reader.Read when the Reader's read deadline
+// passes. Like the errors returned by a net.Conn in the same situation, it has
+// a Timeout method that returns true.
+var ErrDeadlineExceeded error = deadlineExceededError{}
+
+type deadlineExceededError struct{}
+
+func (deadlineExceededError) Error() string {
+	return "slackio: read deadline exceeded"
+}
+
+func (deadlineExceededError) Timeout() bool {
+	return true
+}
+
+func (deadlineExceededError) Temporary() bool {
+	return true
+}
+
+// ReaderOption configures optional behavior for a Reader.
+type ReaderOption func(*Reader)
+
+// ReaderOptionTranscript causes a Reader to format its output as a chat
+// transcript. Each message is prefixed with the ID of the user who sent it,
+// followed by a colon and a space. When consecutive messages come from the same
+// user, only the first is prefixed.
+func ReaderOptionTranscript() ReaderOption {
+	return func(r *Reader) {
+		r.transcript = true
+	}
+}
+
+// ReaderOptionFormatter causes a Reader to output each message in the format
+// produced by the given MessageFormatter, rather than as its text followed by a
+// newline. Line endings within the message's text are normalized before it is
+// formatted, as determined by ReaderOptionNormalizeNewlines.
+// ReaderOptionTranscript has no effect on a Reader with a formatter.
+func ReaderOptionFormatter(f MessageFormatter) ReaderOption {
+	return func(r *Reader) {
+		r.formatter = f
+	}
+}
+
+// ReaderOptionEditedSuffix causes a Reader to append the given suffix, such as
+// " (edited)", to the text of each message that represents an edit to an
+// earlier message. Edits are only distributed by a Client configured with
+// ClientOptionEdits.
+func ReaderOptionEditedSuffix(suffix string) ReaderOption {
+	return func(r *Reader) {
+		r.editedSuffix = suffix
+	}
+}
+
+// ReaderOptionTap causes a Reader to write a copy of all bytes returned by Read
+// to the given io.Writer, in the manner of io.TeeReader, which is useful for
+// debugging the formatting of its output. Unlike io.TeeReader, errors from tap
+// are ignored so that they do not interrupt reading.
+func ReaderOptionTap(tap io.Writer) ReaderOption {
+	return func(r *Reader) {
+		r.tap = tap
+	}
+}
+
+// ReaderOptionIdleTimeout causes a Reader to close itself if no Read occurs
+// for the given duration, so that abandoned Readers do not leak subscriptions.
+// The timeout is reset each time a Read returns, and does not elapse while a
+// Read is in progress. After the Reader closes itself, Read will return EOF and
+// Close will have no further effect.
+func ReaderOptionIdleTimeout(d time.Duration) ReaderOption {
+	return func(r *Reader) {
+		r.idleTimeout = d
+	}
+}
+
+// ReaderOptionNormalizeNewlines determines whether a Reader converts Windows
+// ("\r\n") and classic Mac ("\r") line endings within each message to "\n"
+// before outputting it. Normalization is enabled by default.
+func ReaderOptionNormalizeNewlines(enabled bool) ReaderOption {
+	return func(r *Reader) {
+		r.preserveNewlines = !enabled
+	}
+}
+
+// ReaderOptionOnGap causes a Reader to invoke the given callback whenever it
+// detects that messages were lost between consecutive messages it received,
+// such as when its subscription is skipped forward after falling behind (see
+// SubscribeAt). The callback receives the number of messages that were missed,
+// which may include messages from channels that the Reader would not output.
+// It is invoked from the Reader's internal goroutine, and should return
+// promptly.
+func ReaderOptionOnGap(callback func(missed int)) ReaderOption {
+	return func(r *Reader) {
+		r.onGap = callback
+	}
+}
+
+// ReaderOptionOnlyUsers causes a Reader to output only messages sent by the
+// users with the given IDs. It may not be combined with
+// ReaderOptionExcludeUsers.
+func ReaderOptionOnlyUsers(userIDs ...string) ReaderOption {
+	return func(r *Reader) {
+		r.onlyUsers = userSet(userIDs)
+	}
+}
+
+// ReaderOptionExcludeUsers causes a Reader to omit messages sent by the users
+// with the given IDs. It may not be combined with ReaderOptionOnlyUsers.
+func ReaderOptionExcludeUsers(userIDs ...string) ReaderOption {
+	return func(r *Reader) {
+		r.excludeUsers = userSet(userIDs)
+	}
+}
+
+func userSet(userIDs []string) map[string]bool {
+	set := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		set[id] = true
+	}
+	return set
+}
+
+// ReaderOptionContains causes a Reader to output only messages whose text
+// contains at least one of the given substrings, such as keywords for alerts.
+// See ReaderOptionContainsFold for a case-insensitive version. Only the last of
+// these options given to a Reader takes effect.
+func ReaderOptionContains(substrings ...string) ReaderOption {
+	return func(r *Reader) {
+		r.contains = substrings
+		r.containsFold = false
+	}
+}
+
+// ReaderOptionContainsFold causes a Reader to output only messages whose text
+// contains at least one of the given substrings, without regard to case.
+func ReaderOptionContainsFold(substrings ...string) ReaderOption {
+	lower := make([]string, len(substrings))
+	for i, s := range substrings {
+		lower[i] = strings.ToLower(s)
+	}
+
+	return func(r *Reader) {
+		r.contains = lower
+		r.containsFold = true
+	}
+}
+
+// ReaderOptionReplies causes a Reader to output only thread replies to messages
+// that its Client sent, rather than messages from the main body of a channel.
+// The Client must be configured with ClientOptionTrackReplies.
+func ReaderOptionReplies() ReaderOption {
+	return func(r *Reader) {
+		r.replies = true
+	}
+}
+
+// ReaderOptionSince causes a Reader to omit any message sent before the given
+// time, according to its Timestamp, even if the message is still buffered in
+// its client. This is useful for a Reader that resumes from an earlier point in
+// the stream (see SubscribeAt) but should ignore stale messages. Messages
+// without a valid Timestamp are not omitted.
+func ReaderOptionSince(t time.Time) ReaderOption {
+	return func(r *Reader) {
+		r.since = t
+	}
+}
+
+// ReaderOptionResubscribe causes a Reader to subscribe to its client again if
+// its subscription ends for any reason other than a call to Close, such as a
+// call to Client.UnsubscribeAll while an application reconfigures itself. The
+// new subscription begins immediately after the last message that the Reader
+// received, so that messages still in the client's buffer are not lost (see
+// SubscribeAt). The client must implement SubscribeAt and SubscriptionDone as
+// Client does, or NewReader will panic.
+func ReaderOptionResubscribe() ReaderOption {
+	return func(r *Reader) {
+		r.resubscribe = true
+	}
+}
+
+// ReaderOptionDedupeWindow causes a Reader to omit any message whose text is
+// identical to that of a message it output from the same channel within the
+// given window. This suppresses alerts and other messages that integrations
+// post repeatedly. Omitted duplicates do not extend the window.
+func ReaderOptionDedupeWindow(window time.Duration) ReaderOption {
+	return func(r *Reader) {
+		r.dedupeWindow = window
+	}
+}
+
+// ReaderOptionMaxRate causes a Reader to output at most perSecond messages per
+// second, so that a busy channel remains readable. When messages arrive faster
+// than this, the Reader holds the newest one until it may be output, and drops
+// those that it replaces. If onDrop is non-nil, it is invoked with the number of
+// messages dropped each time a held message is output after others were
+// dropped. It is invoked from the Reader's internal goroutine, and should return
+// promptly. perSecond must be positive, or ReaderOptionMaxRate will panic.
+func ReaderOptionMaxRate(perSecond float64, onDrop func(dropped int)) ReaderOption {
+	if perSecond <= 0 {
+		panic(errors.New("slackio: Reader maximum rate must be positive"))
+	}
+
+	return func(r *Reader) {
+		r.rateInterval = time.Duration(float64(time.Second) / perSecond)
+		r.onRateDrop = onDrop
+	}
+}
+
+// ReaderOptionHeartbeat causes a Reader to invoke the given callback whenever
+// interval elapses without a message from its channel, so that consumers can
+// distinguish a quiet channel from a stalled connection. Heartbeats are reported
+// separately from the Reader's output so that they cannot be mistaken for real
+// messages. The callback is invoked synchronously within the Reader's internal
+// goroutine, and should return promptly.
+func ReaderOptionHeartbeat(interval time.Duration, callback func()) ReaderOption {
+	return func(r *Reader) {
+		r.heartbeatInterval = interval
+		r.onHeartbeat = callback
+	}
+}
+
+// ReaderOptionRecover causes a Reader to recover from any panic in its internal
+// goroutines, such as one raised by a ReaderOptionOnGap callback, and pass the
+// panic's value to the given handler instead of crashing the program. A panic
+// while processing a single message causes only that message to be dropped.
+// By default, such panics are not recovered.
+func ReaderOptionRecover(handler func(interface{})) ReaderOption {
+	return func(r *Reader) {
+		r.recoverHandler = handler
+	}
+}
+
+// defaultReaderBufferSize is the number of bytes of output that a Reader
+// buffers ahead of its consumer, unless overridden with ReaderOptionBufferSize.
+const defaultReaderBufferSize = 64 * 1024
+
+// ReaderOptionBufferSize sets the number of bytes of output that a Reader
+// buffers ahead of its consumer. While the buffer has room, the Reader keeps
+// receiving messages from its client even if Read is not being called, which
+// helps it to tolerate bursts of messages without falling behind its
+// subscription (see SubscribeAt). A single message larger than the buffer is
+// still accepted once the buffer is empty. n must be positive, or
+// ReaderOptionBufferSize will panic.
+func ReaderOptionBufferSize(n int) ReaderOption {
+	if n <= 0 {
+		panic(errors.New("slackio: Reader buffer size must be positive"))
+	}
+
+	return func(r *Reader) {
+		r.bufferSize = n
+	}
+}
+
+// newlineReplacer normalizes line endings to "\n".
+var newlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
 // Reader reads messages from the main body of one or more Slack channels.
 type Reader struct {
 	client    ReadClient
 	channelID string
 	msgCh     chan Message
 	wg        sync.WaitGroup
-	readOut   io.ReadCloser
-	readIn    io.WriteCloser
+	buffer    *readBuffer
+
+	closeOnce sync.Once
+
+	transcript       bool
+	lastUserID       string
+	preserveNewlines bool
+	formatter        MessageFormatter
+	editedSuffix     string
+	tap              io.Writer
+
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+
+	heartbeatInterval time.Duration
+	onHeartbeat       func()
+
+	onGap func(missed int)
+
+	onlyUsers    map[string]bool
+	excludeUsers map[string]bool
+
+	replies bool
+	since   time.Time
+
+	// contains is nil unless the Reader filters by substring. If containsFold
+	// is set, its entries are in lower case.
+	contains     []string
+	containsFold bool
+
+	// recent maps the channel and text of each message output within the dedupe
+	// window to the time at which it was output.
+	dedupeWindow time.Duration
+	recent       map[dedupeKey]time.Time
+
+	// The rate limiting state is only accessed by the Reader's internal
+	// goroutine. pending is the newest message held back by the rate limit,
+	// which is output when rateTimer fires.
+	rateInterval   time.Duration
+	onRateDrop     func(dropped int)
+	lastOutput     time.Time
+	hasOutput      bool
+	pending        *Message
+	pendingDropped int
+	rateTimer      <-chan time.Time
+
+	bufferSize int
+
+	recoverHandler func(interface{})
+
+	// resubscribeLock keeps the Reader from subscribing again once Close has
+	// begun to unsubscribe it.
+	resubscribe     bool
+	resubscribeLock sync.Mutex
+	closing         bool
+}
+
+// resubscribeClient represents the methods that a ReadClient must implement
+// for use with ReaderOptionResubscribe.
+type resubscribeClient interface {
+	SubscribeAt(id int, ch chan<- Message) error
+	SubscriptionDone(ch chan<- Message) <-chan struct{}
 }
 
 // NewReader returns a new Reader. If channelID is non-blank, the Reader will
 // only output text from a single channel. Otherwise, it will output text from
-// all channels together in a single stream.
-func NewReader(client ReadClient, channelID string) *Reader {
+// all channels together in a single stream. Any provided options are applied in
+// order.
+//
+// If the Reader cannot subscribe to the client, or the provided options are
+// invalid, NewReader will panic. See NewReaderChecked for a version that
+// returns the error instead.
+func NewReader(client ReadClient, channelID string, opts ...ReaderOption) *Reader {
+	c, err := NewReaderChecked(client, channelID, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// NewReaderChecked returns a new Reader exactly as NewReader does, but returns
+// any error encountered while validating its options or subscribing to the
+// client rather than panicking.
+func NewReaderChecked(client ReadClient, channelID string, opts ...ReaderOption) (*Reader, error) {
 	c := &Reader{
 		client:    client,
 		channelID: channelID,
 		msgCh:     make(chan Message, 1),
+
+		bufferSize: defaultReaderBufferSize,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.onlyUsers != nil && c.excludeUsers != nil {
+		return nil, errors.New("slackio: ReaderOptionOnlyUsers and ReaderOptionExcludeUsers are mutually exclusive")
+	}
+
+	resubClient, ok := c.client.(resubscribeClient)
+	if c.resubscribe && !ok {
+		return nil, errors.New("slackio: ReaderOptionResubscribe is not supported by this client")
+	}
+
+	if err := c.client.Subscribe(c.msgCh); err != nil {
+		return nil, err
+	}
+
+	var ended <-chan struct{}
+	if c.resubscribe {
+		ended = resubClient.SubscriptionDone(c.msgCh)
 	}
 
-	c.readOut, c.readIn = io.Pipe()
-	c.client.Subscribe(c.msgCh)
+	c.buffer = newReadBuffer(c.bufferSize)
 
 	// Process incoming reads from the Client; note that the stream channel
 	// will be drained until it is closed
@@ -41,19 +399,270 @@ func NewReader(client ReadClient, channelID string) *Reader {
 	go func() {
 		defer c.wg.Done()
 
-		for msg := range c.msgCh {
-			if c.channelID != "" && msg.ChannelID != c.channelID {
-				continue
+		heartbeat := c.nextHeartbeat()
+		lastID := -1
+
+		// receive processes a message from the Client, and reports whether the
+		// message was accepted for this Reader's output.
+		receive := func(msg Message) (accepted bool) {
+			gap := lastID >= 0 && msg.ID > lastID+1
+			missed := msg.ID - lastID - 1
+			lastID = msg.ID
+
+			defer c.recoverPanic()
+
+			if c.onGap != nil && gap {
+				c.onGap(missed)
 			}
 
-			// When this Reader is closed, this call returns an io.ErrClosedPipe.
-			// This is the only possible error if we don't close readOut, and it can
-			// be safely ignored.
-			c.readIn.Write(append([]byte(msg.Text), byte('\n')))
+			if c.accepts(msg) && !c.isDuplicate(msg) {
+				accepted = true
+				c.output(msg)
+			}
+			return
+		}
+
+		for {
+			// When this Reader is closed, writes return an io.ErrClosedPipe. This is
+			// the only possible error, and it can be safely ignored.
+			//
+			// The heartbeat is postponed only by messages that this Reader accepts,
+			// so that traffic that it filters out cannot hide a stalled channel.
+			select {
+			case msg, ok := <-c.msgCh:
+				if !ok {
+					return
+				}
+				if receive(msg) {
+					heartbeat = c.nextHeartbeat()
+				}
+
+			case <-c.rateTimer:
+				c.rateTimer = nil
+				func() {
+					defer c.recoverPanic()
+					c.outputPending()
+				}()
+
+			case <-heartbeat:
+				func() {
+					defer c.recoverPanic()
+					c.onHeartbeat()
+				}()
+				heartbeat = c.nextHeartbeat()
+
+			case <-ended:
+				// The subscription may have delivered messages that are still
+				// buffered, which must be processed before resubscribing after the
+				// last of them.
+			drain:
+				for {
+					select {
+					case msg, ok := <-c.msgCh:
+						if !ok {
+							return
+						}
+						if receive(msg) {
+							heartbeat = c.nextHeartbeat()
+						}
+					default:
+						break drain
+					}
+				}
+
+				ended = c.subscribeAgain(resubClient, lastID)
+			}
 		}
 	}()
 
-	return c
+	if c.idleTimeout > 0 {
+		c.idleTimer = time.AfterFunc(c.idleTimeout, func() {
+			defer c.recoverPanic()
+			c.closeOnce.Do(c.close)
+		})
+	}
+
+	return c, nil
+}
+
+// subscribeAgain implements ReaderOptionResubscribe, by subscribing to client
+// immediately after lastID unless the Reader is closing. It returns a channel
+// that is closed when the new subscription ends, or nil if there is none.
+func (c *Reader) subscribeAgain(client resubscribeClient, lastID int) <-chan struct{} {
+	c.resubscribeLock.Lock()
+	defer c.resubscribeLock.Unlock()
+
+	if c.closing {
+		return nil
+	}
+
+	id := -1
+	if lastID >= 0 {
+		id = lastID + 1
+	}
+
+	if err := client.SubscribeAt(id, c.msgCh); err != nil {
+		return nil
+	}
+	return client.SubscriptionDone(c.msgCh)
+}
+
+// recoverPanic, when deferred, recovers from a panic in one of this Reader's
+// internal goroutines and passes it to the Reader's recover handler, if the
+// Reader has one.
+func (c *Reader) recoverPanic() {
+	if c.recoverHandler == nil {
+		return
+	}
+
+	if v := recover(); v != nil {
+		c.recoverHandler(v)
+	}
+}
+
+// accepts reports whether msg should be included in this Reader's output.
+func (c *Reader) accepts(msg Message) bool {
+	if c.channelID != "" && msg.ChannelID != c.channelID {
+		return false
+	}
+
+	if isReply := msg.ThreadTimestamp != ""; isReply != c.replies {
+		return false
+	}
+
+	if c.onlyUsers != nil && !c.onlyUsers[msg.UserID] {
+		return false
+	}
+
+	if sent, ok := msg.Time(); ok && sent.Before(c.since) {
+		return false
+	}
+
+	if c.contains != nil && !c.containsAny(msg.Text) {
+		return false
+	}
+
+	return !c.excludeUsers[msg.UserID]
+}
+
+// containsAny reports whether text contains any of the substrings that this
+// Reader filters by.
+func (c *Reader) containsAny(text string) bool {
+	if c.containsFold {
+		text = strings.ToLower(text)
+	}
+
+	for _, s := range c.contains {
+		if strings.Contains(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type dedupeKey struct {
+	channelID string
+	text      string
+}
+
+// isDuplicate reports whether msg duplicates a message that this Reader output
+// within its dedupe window, and otherwise records it as output. Only the
+// Reader's internal goroutine may call isDuplicate.
+func (c *Reader) isDuplicate(msg Message) bool {
+	if c.dedupeWindow <= 0 {
+		return false
+	}
+
+	now := timeNow()
+	for key, seen := range c.recent {
+		if now.Sub(seen) >= c.dedupeWindow {
+			delete(c.recent, key)
+		}
+	}
+
+	key := dedupeKey{msg.ChannelID, msg.Text}
+	if _, ok := c.recent[key]; ok {
+		return true
+	}
+
+	if c.recent == nil {
+		c.recent = make(map[dedupeKey]time.Time)
+	}
+	c.recent[key] = now
+	return false
+}
+
+// output writes msg to this Reader's output, subject to its rate limit.
+func (c *Reader) output(msg Message) {
+	if c.rateInterval <= 0 {
+		c.buffer.Write(c.format(msg))
+		return
+	}
+
+	if c.pending != nil {
+		c.pending = &msg
+		c.pendingDropped++
+		return
+	}
+
+	now := timeNow()
+	if next := c.lastOutput.Add(c.rateInterval); c.hasOutput && now.Before(next) {
+		c.pending = &msg
+		c.rateTimer = timeAfter(next.Sub(now))
+		return
+	}
+
+	c.lastOutput, c.hasOutput = now, true
+	c.buffer.Write(c.format(msg))
+}
+
+// outputPending writes the message held back by this Reader's rate limit.
+func (c *Reader) outputPending() {
+	msg, dropped := *c.pending, c.pendingDropped
+	c.pending, c.pendingDropped = nil, 0
+	c.lastOutput = timeNow()
+
+	if dropped > 0 && c.onRateDrop != nil {
+		c.onRateDrop(dropped)
+	}
+
+	c.buffer.Write(c.format(msg))
+}
+
+// nextHeartbeat returns a channel that receives when the next heartbeat is due,
+// or nil if heartbeats are disabled.
+func (c *Reader) nextHeartbeat() <-chan time.Time {
+	if c.heartbeatInterval <= 0 || c.onHeartbeat == nil {
+		return nil
+	}
+
+	return timeAfter(c.heartbeatInterval)
+}
+
+// format returns the bytes that represent a single message in this Reader's
+// output.
+func (c *Reader) format(msg Message) []byte {
+	if !c.preserveNewlines {
+		msg.Text = newlineReplacer.Replace(msg.Text)
+	}
+
+	if msg.Edited {
+		msg.Text += c.editedSuffix
+	}
+
+	if c.formatter != nil {
+		return c.formatter.Format(msg)
+	}
+
+	var out []byte
+
+	if c.transcript && msg.UserID != c.lastUserID {
+		out = append(out, msg.UserID+": "...)
+	}
+	c.lastUserID = msg.UserID
+
+	out = append(out, msg.Text...)
+	return append(out, '\n')
 }
 
 // Read returns text from the main body of one or more Slack channels (i.e.
@@ -61,23 +670,178 @@ func NewReader(client ReadClient, channelID string) *Reader {
 // with an appended newline. Messages with explicit line breaks are equivalent
 // to multiple single messages in succession.
 func (c *Reader) Read(p []byte) (int, error) {
-	return c.readOut.Read(p)
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		defer c.idleTimer.Reset(c.idleTimeout)
+	}
+
+	n, err := c.buffer.Read(p)
+	if n > 0 && c.tap != nil {
+		c.tap.Write(p[:n])
+	}
+	return n, err
+}
+
+// SetReadDeadline sets the time after which Read fails with
+// ErrDeadlineExceeded, including for a Read that is already in progress, in the
+// manner of net.Conn. After the deadline passes, Read continues to fail even if
+// messages arrive, until the deadline is extended. A zero value for t means
+// that Read will not time out.
+func (c *Reader) SetReadDeadline(t time.Time) error {
+	c.buffer.setDeadline(t)
+	return nil
 }
 
 // Close disconnects this Reader from Slack and shuts down internal buffers.
-// After calling Close, the next call to Read will result in an EOF.
+// After calling Close, the next call to Read will result in an EOF. Subsequent
+// calls to Close have no effect.
 func (c *Reader) Close() error {
-	if err := c.client.Unsubscribe(c.msgCh); err != nil {
-		// This is a catastrophic situation likely indicating corruption of the
-		// Client's subscription pool.
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+
+	c.closeOnce.Do(c.close)
+	return nil
+}
+
+func (c *Reader) close() {
+	c.resubscribeLock.Lock()
+	c.closing = true
+	err := c.client.Unsubscribe(c.msgCh)
+	c.resubscribeLock.Unlock()
+
+	// With ReaderOptionResubscribe, the subscription may have ended before the
+	// Reader could renew it. Otherwise, this is a catastrophic situation likely
+	// indicating corruption of the Client's subscription pool.
+	if err != nil && !(c.resubscribe && err == ErrNotSubscribed) {
 		panic(err)
 	}
 
-	// Closing the write half of the pipe forces Read to return EOF and Write
-	// to return ErrClosedPipe. The call itself always returns nil.
-	c.readIn.Close()
+	// Closing the buffer forces Read to return EOF and Write to return
+	// ErrClosedPipe, discarding any output that has yet to be read.
+	c.buffer.Close()
 	close(c.msgCh)
 	c.wg.Wait()
+}
+
+// readBuffer holds a bounded amount of a Reader's output ahead of its consumer.
+// Like an io.Pipe, each Read returns data from at most one Write, so that a
+// Reader's output continues to be delivered one message at a time to callers
+// that provide large enough buffers.
+type readBuffer struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	size   int
+	limit  int
+	closed bool
+
+	// deadlineTimer wakes any pending Read when the deadline passes. Like those
+	// of a net.Conn, deadlines are measured with the real clock.
+	deadline      time.Time
+	deadlineTimer *time.Timer
+}
+
+func newReadBuffer(limit int) *readBuffer {
+	b := &readBuffer{limit: limit}
+	b.cond = sync.NewCond(&b.lock)
+	return b
+}
+
+// Write blocks until the buffer has room for all of p, or is empty, and then
+// buffers a copy of p. It returns io.ErrClosedPipe if the buffer is closed.
+func (b *readBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for b.size > 0 && b.size+len(p) > b.limit && !b.closed {
+		b.cond.Wait()
+	}
+
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if len(p) > 0 {
+		b.chunks = append(b.chunks, append([]byte(nil), p...))
+		b.size += len(p)
+		b.cond.Broadcast()
+	}
+
+	return len(p), nil
+}
+
+// Read blocks until data is available, and then reads from the earliest
+// buffered Write. It returns EOF once the buffer is closed, even if data
+// remains unread, and ErrDeadlineExceeded once its deadline has passed.
+func (b *readBuffer) Read(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for {
+		if b.closed {
+			return 0, io.EOF
+		}
+
+		if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+			return 0, ErrDeadlineExceeded
+		}
+
+		if len(b.chunks) > 0 {
+			break
+		}
+
+		b.cond.Wait()
+	}
+
+	n := copy(p, b.chunks[0])
+	b.chunks[0] = b.chunks[0][n:]
+	if len(b.chunks[0]) == 0 {
+		b.chunks[0] = nil
+		b.chunks = b.chunks[1:]
+	}
+
+	b.size -= n
+	b.cond.Broadcast()
+	return n, nil
+}
+
+// setDeadline sets the time after which Read fails, and wakes any pending Read
+// so that it observes the change.
+func (b *readBuffer) setDeadline(t time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.deadlineTimer != nil {
+		b.deadlineTimer.Stop()
+		b.deadlineTimer = nil
+	}
+
+	b.deadline = t
+	if !t.IsZero() {
+		b.deadlineTimer = time.AfterFunc(time.Until(t), func() {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			b.cond.Broadcast()
+		})
+	}
+
+	b.cond.Broadcast()
+}
+
+// Close discards any buffered data, and unblocks all pending calls to Read and
+// Write.
+func (b *readBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.deadlineTimer != nil {
+		b.deadlineTimer.Stop()
+	}
 
+	b.closed = true
+	b.chunks = nil
+	b.size = 0
+	b.cond.Broadcast()
 	return nil
 }
@@ -4,20 +4,31 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
 )
 
 type testReadClient struct {
 	messages  []Message
+	start     chan struct{}
 	wg        sync.WaitGroup
 	doneChans map[chan<- Message]chan struct{}
+	subErr    error
 	unsubErr  error
 }
 
 // Subscribe in this test implementation just sends a predefined set of
-// messages into a channel.
+// messages into a channel. If the start channel is set, sending is delayed
+// until it is closed.
 func (c *testReadClient) Subscribe(ch chan<- Message) error {
+	if c.subErr != nil {
+		return c.subErr
+	}
+
 	if c.doneChans == nil {
 		c.doneChans = make(map[chan<- Message]chan struct{})
 	}
@@ -30,6 +41,10 @@ func (c *testReadClient) Subscribe(ch chan<- Message) error {
 		defer c.wg.Done()
 		defer close(done)
 
+		if c.start != nil {
+			<-c.start
+		}
+
 		for _, m := range c.messages {
 			ch <- m
 		}
@@ -187,3 +202,858 @@ func TestReaderPanicsOnUnsubscribeError(t *testing.T) {
 	r := NewReader(client, "")
 	r.Close()
 }
+
+func TestReaderSubscribeError(t *testing.T) {
+	subErr := errors.New("test Subscribe error")
+	client := &testReadClient{subErr: subErr}
+
+	if r, err := NewReaderChecked(client, ""); r != nil || err != subErr {
+		t.Fatalf("unexpected NewReaderChecked result on Subscribe error: %v, %v", r, err)
+	}
+
+	defer func() {
+		if err := recover(); err != subErr {
+			t.Fatalf("unexpected NewReader panic on Subscribe error: %v", err)
+		}
+	}()
+
+	NewReader(client, "")
+}
+
+func TestTranscriptReader(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "hello", UserID: "U11111111"},
+			{Text: "anyone around?", UserID: "U11111111"},
+			{Text: "hi there", UserID: "U22222222"},
+			{Text: "great", UserID: "U11111111"},
+			{Text: "what's up?", UserID: "U11111111"},
+		},
+	}
+
+	r := NewReader(client, "", ReaderOptionTranscript())
+
+	expected := "U11111111: hello\nanyone around?\nU22222222: hi there\nU11111111: great\nwhat's up?\n"
+	actual := make([]byte, len(expected))
+
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	client.wait()
+}
+
+func TestReaderIdleTimeout(t *testing.T) {
+	client := &testReadClient{}
+	r := NewReader(client, "", ReaderOptionIdleTimeout(10*time.Millisecond))
+
+	// Without any reads, the Reader should close itself well within this time.
+	time.Sleep(50 * time.Millisecond)
+
+	var readBytes [16]byte
+	if _, err := r.Read(readBytes[:]); err != io.EOF {
+		t.Fatalf("unexpected Reader error after idle timeout: %v (expected EOF)", err)
+	}
+
+	if len(client.doneChans) != 0 {
+		t.Fatal("Reader did not unsubscribe after idle timeout")
+	}
+
+	// An explicit Close after the timeout must be safe.
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	client.wait()
+}
+
+func TestReaderHeartbeat(t *testing.T) {
+	timers := make(chan chan time.Time, 1)
+	timeAfter = func(_ time.Duration) <-chan time.Time {
+		timer := make(chan time.Time, 1)
+		timers <- timer
+		return timer
+	}
+	defer func() { timeAfter = time.After }()
+
+	client := initClient()
+	defer client.Close()
+
+	beats := make(chan struct{}, 4)
+	r := NewReader(client, "", ReaderOptionHeartbeat(time.Minute, func() { beats <- struct{}{} }))
+	defer r.Close()
+
+	expectRead := func(expected string) {
+		t.Helper()
+
+		buf := make([]byte, 64)
+		n, err := r.Read(buf)
+		if err != nil {
+			t.Fatalf("unexpected Reader error: %v", err)
+		}
+		if out := string(buf[:n]); out != expected {
+			t.Fatalf("unexpected Reader output %q (expected %q)", out, expected)
+		}
+	}
+
+	// The channel is silent, so a heartbeat is reported.
+	(<-timers) <- time.Now()
+	<-beats
+
+	// A real message postpones the next heartbeat, so firing the timer that was
+	// pending beforehand must not report one.
+	stale := <-timers
+	msg := slack.Msg{Type: "message", Channel: "C12345678", Text: "hello"}
+	evt := slack.MessageEvent(slack.Message{Msg: msg})
+	client.distribute(&evt)
+	expectRead("hello\n")
+
+	current := <-timers
+	stale <- time.Now()
+	client.distribute(&evt)
+	expectRead("hello\n")
+
+	latest := <-timers
+	current <- time.Now()
+	latest <- time.Now()
+	<-beats
+
+	// Heartbeats never appear in the Reader's output.
+	<-timers
+	client.distribute(&evt)
+	expectRead("hello\n")
+	if len(beats) > 0 {
+		t.Fatal("reported a heartbeat for a stale timer")
+	}
+}
+
+func TestReaderHeartbeatOtherChannels(t *testing.T) {
+	timers := make(chan chan time.Time, 4)
+	timeAfter = func(_ time.Duration) <-chan time.Time {
+		timer := make(chan time.Time, 1)
+		timers <- timer
+		return timer
+	}
+	defer func() { timeAfter = time.After }()
+
+	client := initClient()
+	defer client.Close()
+
+	beats := make(chan struct{}, 1)
+	r := NewReader(client, "C12345678", ReaderOptionHeartbeat(time.Minute, func() { beats <- struct{}{} }))
+	defer r.Close()
+
+	// Traffic on other channels must not postpone the heartbeat for this one.
+	timer := <-timers
+	for i := 0; i < 3; i++ {
+		msg := slack.Msg{Type: "message", Channel: "C87654321", Text: "elsewhere"}
+		evt := slack.MessageEvent(slack.Message{Msg: msg})
+		client.distribute(&evt)
+	}
+
+	select {
+	case <-timers:
+		t.Fatal("postponed heartbeat for a message on another channel")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	timer <- time.Now()
+	select {
+	case <-beats:
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat despite silence on the Reader's channel")
+	}
+}
+
+func TestReaderEditedSuffix(t *testing.T) {
+	client := initClient(ClientOptionEdits())
+	defer client.Close()
+
+	r := NewReader(client, "", ReaderOptionEditedSuffix(" (edited)"))
+	defer r.Close()
+
+	msg := slack.Msg{Type: "message", Channel: "C12345678", Text: "old text", Timestamp: "1234.5678"}
+	evt := slack.MessageEvent(slack.Message{Msg: msg})
+	client.distribute(&evt)
+
+	edit := slack.MessageEvent(slack.Message{
+		Msg:             slack.Msg{Type: "message", SubType: "message_changed", Channel: "C12345678"},
+		SubMessage:      &slack.Msg{Type: "message", Text: "new text", Timestamp: "1234.5678"},
+		PreviousMessage: &slack.Msg{Type: "message", Text: "old text", Timestamp: "1234.5678"},
+	})
+	client.distribute(&edit)
+
+	msg = slack.Msg{Type: "message", Channel: "C12345678", Text: "more text", Timestamp: "1234.6789"}
+	evt = slack.MessageEvent(slack.Message{Msg: msg})
+	client.distribute(&evt)
+
+	expected := "old text\nnew text (edited)\nmore text\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+}
+
+func TestReaderPrivateChannel(t *testing.T) {
+	client := initClient()
+	defer client.Close()
+
+	r := NewReader(client, "G12345678")
+	defer r.Close()
+
+	for _, channelID := range []string{"C12345678", "G12345678"} {
+		msg := slack.Msg{Type: "message", Channel: channelID, Text: "hello from " + channelID}
+		evt := slack.MessageEvent(slack.Message{Msg: msg})
+		client.distribute(&evt)
+	}
+
+	expected := "hello from G12345678\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if typ := client.messages[1].ChannelType; typ != ChannelTypePrivate {
+		t.Fatalf("unexpected channel type %q (expected %q)", typ, ChannelTypePrivate)
+	}
+}
+
+func TestReaderNormalizeNewlines(t *testing.T) {
+	cases := []struct {
+		description string
+		opts        []ReaderOption
+		expected    string
+	}{
+		{
+			"normalizes line endings by default",
+			nil,
+			"first\nsecond\nthird\n",
+		},
+		{
+			"preserves line endings when disabled",
+			[]ReaderOption{ReaderOptionNormalizeNewlines(false)},
+			"first\r\nsecond\rthird\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := &testReadClient{
+				messages: []Message{{Text: "first\r\nsecond\rthird"}},
+			}
+
+			r := NewReader(client, "", tc.opts...)
+
+			actual := make([]byte, len(tc.expected))
+			if _, err := io.ReadFull(r, actual); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+
+			if string(actual) != tc.expected {
+				t.Fatalf("unexpected Reader output: %q (expected %q)", actual, tc.expected)
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+
+			client.wait()
+		})
+	}
+}
+
+func TestReaderOnGap(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{ID: 3, Text: "first", ChannelID: "C12345678"},
+			{ID: 4, Text: "second", ChannelID: "C12345678"},
+			{ID: 9, Text: "other channel", ChannelID: "C87654321"},
+			{ID: 12, Text: "third", ChannelID: "C12345678"},
+		},
+	}
+
+	var gaps []int
+	r := NewReader(client, "C12345678", ReaderOptionOnGap(func(missed int) {
+		gaps = append(gaps, missed)
+	}))
+
+	expected := "first\nsecond\nthird\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+
+	if !reflect.DeepEqual(gaps, []int{4, 2}) {
+		t.Fatalf("unexpected gaps %v (expected [4 2])", gaps)
+	}
+}
+
+func TestReaderUserFilters(t *testing.T) {
+	cases := []struct {
+		description string
+		opt         ReaderOption
+		expected    string
+	}{
+		{
+			"includes only listed users",
+			ReaderOptionOnlyUsers("U11111111", "U33333333"),
+			"one\nthree\n",
+		},
+		{
+			"excludes listed users",
+			ReaderOptionExcludeUsers("U11111111"),
+			"two\nthree\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := &testReadClient{
+				messages: []Message{
+					{Text: "one", UserID: "U11111111"},
+					{Text: "two", UserID: "U22222222"},
+					{Text: "three", UserID: "U33333333"},
+				},
+			}
+
+			r := NewReader(client, "", tc.opt)
+
+			actual := make([]byte, len(tc.expected))
+			if _, err := io.ReadFull(r, actual); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+
+			if string(actual) != tc.expected {
+				t.Fatalf("unexpected Reader output: %q (expected %q)", actual, tc.expected)
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+
+			client.wait()
+		})
+	}
+}
+
+func TestReaderContains(t *testing.T) {
+	messages := []Message{
+		{Text: "disk full on db1", ChannelID: "C12345678"},
+		{Text: "all quiet", ChannelID: "C12345678"},
+		{Text: "Deploy FAILED", ChannelID: "C12345678"},
+		{Text: "lunch?", ChannelID: "C12345678"},
+		{Text: "deploy failed again", ChannelID: "C12345678"},
+	}
+
+	cases := []struct {
+		description string
+		option      ReaderOption
+		expected    string
+	}{
+		{
+			description: "case-sensitive",
+			option:      ReaderOptionContains("disk", "failed"),
+			expected:    "disk full on db1\ndeploy failed again\n",
+		},
+		{
+			description: "case-insensitive",
+			option:      ReaderOptionContainsFold("DISK", "failed"),
+			expected:    "disk full on db1\nDeploy FAILED\ndeploy failed again\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := &testReadClient{messages: messages}
+			r := NewReader(client, "", tc.option)
+
+			actual := make([]byte, len(tc.expected))
+			if _, err := io.ReadFull(r, actual); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("unexpected Reader error: %q", err.Error())
+			}
+			client.wait()
+
+			if string(actual) != tc.expected {
+				t.Fatalf("unexpected Reader output: %q (expected %q)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReaderConflictingUserFilters(t *testing.T) {
+	client := &testReadClient{}
+
+	_, err := NewReaderChecked(client, "",
+		ReaderOptionOnlyUsers("U11111111"),
+		ReaderOptionExcludeUsers("U22222222"),
+	)
+	if err == nil {
+		t.Fatal("NewReaderChecked accepted mutually exclusive user filters")
+	}
+
+	if len(client.doneChans) > 0 {
+		t.Fatal("NewReaderChecked subscribed despite invalid options")
+	}
+}
+
+func TestReaderBuffersBursts(t *testing.T) {
+	client := &testReadClient{}
+	for i := 0; i < 100; i++ {
+		client.messages = append(client.messages, Message{ID: i, Text: "burst", ChannelID: "C12345678"})
+	}
+
+	r := NewReader(client, "", ReaderOptionBufferSize(1024))
+
+	// The entire burst fits in the buffer, so the client finishes sending it
+	// without any Read taking place. Test times out if Reader blocks.
+	client.wait()
+
+	var readBytes [16]byte
+	for i := 0; i < len(client.messages); i++ {
+		n, err := r.Read(readBytes[:])
+		if err != nil {
+			t.Fatalf("unexpected Reader error: %q", err.Error())
+		}
+
+		if actual := string(readBytes[:n]); actual != "burst\n" {
+			t.Fatalf("unexpected Reader output: %q (expected %q)", actual, "burst\n")
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if _, err := r.Read(readBytes[:]); err != io.EOF {
+		t.Fatalf("unexpected Reader error: %v (expected EOF)", err)
+	}
+}
+
+func TestReaderBufferIsBounded(t *testing.T) {
+	b := newReadBuffer(8)
+	if _, err := b.Write([]byte("12345678")); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		b.Write([]byte("9"))
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("write to full buffer did not block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	var readBytes [4]byte
+	if n, err := b.Read(readBytes[:]); err != nil || string(readBytes[:n]) != "1234" {
+		t.Fatalf("unexpected read %q, %v (expected %q)", readBytes[:n], err, "1234")
+	}
+
+	<-written // Test times out if the write never completes
+}
+
+func TestReaderRecover(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{ID: 0, Text: "first"},
+			{ID: 2, Text: "after gap"},
+			{ID: 3, Text: "second"},
+		},
+	}
+
+	var recovered []interface{}
+	r := NewReader(client, "",
+		ReaderOptionOnGap(func(int) { panic("bad gap") }),
+		ReaderOptionRecover(func(v interface{}) { recovered = append(recovered, v) }),
+	)
+
+	expected := "first\nsecond\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+
+	if !reflect.DeepEqual(recovered, []interface{}{"bad gap"}) {
+		t.Fatalf("unexpected recovered panics %v (expected [bad gap])", recovered)
+	}
+}
+
+func TestReaderDedupeWindow(t *testing.T) {
+	// Each message arrives one minute after the last.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var calls int
+	timeNow = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Minute)
+	}
+	defer func() { timeNow = time.Now }()
+
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "disk full", ChannelID: "C12345678"},
+			{Text: "disk full", ChannelID: "C12345678"},
+			{Text: "disk full", ChannelID: "C87654321"},
+			{Text: "all clear", ChannelID: "C12345678"},
+			{Text: "disk full", ChannelID: "C12345678"},
+		},
+	}
+
+	r := NewReader(client, "", ReaderOptionDedupeWindow(4*time.Minute))
+
+	expected := "disk full\ndisk full\nall clear\ndisk full\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+}
+
+func TestReaderSince(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "old", ChannelID: "C12345678", Timestamp: "1577836799.999999"},
+			{Text: "exact", ChannelID: "C12345678", Timestamp: "1577836800.000000"},
+			{Text: "stale", ChannelID: "C12345678", Timestamp: "1577836000.123456"},
+			{Text: "untimed", ChannelID: "C12345678"},
+			{Text: "new", ChannelID: "C12345678", Timestamp: "1577836801.500000"},
+		},
+	}
+
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewReader(client, "", ReaderOptionSince(since))
+
+	expected := "exact\nuntimed\nnew\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+}
+
+func TestReaderResubscribe(t *testing.T) {
+	client := initClient()
+	defer client.Close()
+
+	r := NewReader(client, "", ReaderOptionResubscribe())
+
+	distribute := func(text string) {
+		msg := slack.Msg{Type: "message", Channel: "C12345678", Text: text}
+		evt := slack.MessageEvent(slack.Message{Msg: msg})
+		client.distribute(&evt)
+	}
+
+	expectRead := func(expected string) {
+		t.Helper()
+
+		r.SetReadDeadline(time.Now().Add(time.Second))
+		actual := make([]byte, len(expected))
+		if _, err := io.ReadFull(r, actual); err != nil {
+			t.Fatalf("unexpected Reader error: %v", err)
+		}
+		if string(actual) != expected {
+			t.Fatalf("unexpected Reader output %q (expected %q)", actual, expected)
+		}
+	}
+
+	distribute("one")
+	expectRead("one\n")
+
+	// The message sent while the Reader is unsubscribed is still buffered in
+	// the Client, so the Reader picks it up after resubscribing.
+	client.UnsubscribeAll()
+	distribute("two")
+	distribute("three")
+	expectRead("two\nthree\n")
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error on close: %v", err)
+	}
+	if count := client.SubscriptionCount(); count != 0 {
+		t.Fatalf("unexpected subscription count %d after Close", count)
+	}
+}
+
+// holdFormatter formats messages as PlainFormatter does, but first hands a
+// channel to held and waits for it to be closed when formatting a message with
+// the text "hold".
+type holdFormatter struct {
+	held chan chan struct{}
+}
+
+func (f holdFormatter) Format(msg Message) []byte {
+	if msg.Text == "hold" {
+		release := make(chan struct{})
+		f.held <- release
+		<-release
+	}
+	return PlainFormatter{}.Format(msg)
+}
+
+func TestReaderResubscribeBuffered(t *testing.T) {
+	client := initClient()
+	defer client.Close()
+
+	// The formatter holds up the Reader's internal goroutine, so that the
+	// subscription can end with a message still buffered for the Reader.
+	held := make(chan chan struct{})
+	r := NewReader(client, "",
+		ReaderOptionResubscribe(),
+		ReaderOptionFormatter(holdFormatter{held}))
+	defer r.Close()
+
+	distribute := func(text string) {
+		msg := slack.Msg{Type: "message", Channel: "C12345678", Text: text}
+		evt := slack.MessageEvent(slack.Message{Msg: msg})
+		client.distribute(&evt)
+	}
+
+	expectRead := func(expected string) {
+		t.Helper()
+
+		r.SetReadDeadline(time.Now().Add(time.Second))
+		actual := make([]byte, len(expected))
+		if _, err := io.ReadFull(r, actual); err != nil {
+			t.Fatalf("unexpected Reader error: %v", err)
+		}
+		if string(actual) != expected {
+			t.Fatalf("unexpected Reader output %q (expected %q)", actual, expected)
+		}
+	}
+
+	// The Reader may see the end of the subscription before the buffered
+	// message, so try several times to catch it resubscribing too early.
+	for i := 0; i < 10; i++ {
+		distribute("hold")
+		release := <-held
+
+		distribute("buffered")
+		for len(r.msgCh) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		client.UnsubscribeAll()
+		close(release)
+
+		// The buffered message appears exactly once, followed by the next one.
+		expectRead("hold\nbuffered\n")
+		distribute("next")
+		expectRead("next\n")
+	}
+}
+
+func TestReaderResubscribeUnsupported(t *testing.T) {
+	_, err := NewReaderChecked(&testReadClient{}, "", ReaderOptionResubscribe())
+	if err == nil {
+		t.Fatal("NewReaderChecked accepted a client that cannot resubscribe")
+	}
+}
+
+func TestReaderMaxRate(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	timers := make(chan chan time.Time, 1)
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		if d != 500*time.Millisecond {
+			t.Errorf("unexpected rate limit delay %v (expected %v)", d, 500*time.Millisecond)
+		}
+
+		timer := make(chan time.Time, 1)
+		timers <- timer
+		return timer
+	}
+	defer func() { timeAfter = time.After }()
+
+	// The trailing messages from another channel are not output, but ensure that
+	// the entire burst is processed before the client finishes sending.
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "1", ChannelID: "C12345678"},
+			{Text: "2", ChannelID: "C12345678"},
+			{Text: "3", ChannelID: "C12345678"},
+			{Text: "4", ChannelID: "C12345678"},
+			{Text: "other", ChannelID: "C87654321"},
+			{Text: "other", ChannelID: "C87654321"},
+		},
+	}
+
+	var dropped []int
+	r := NewReader(client, "C12345678", ReaderOptionMaxRate(2, func(n int) {
+		dropped = append(dropped, n)
+	}))
+
+	timer := <-timers
+	client.wait()
+
+	now = now.Add(500 * time.Millisecond)
+	timer <- now
+
+	expected := "1\n4\n"
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if string(actual) != expected {
+		t.Fatalf("unexpected Reader output: %q (expected %q)", actual, expected)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if !reflect.DeepEqual(dropped, []int{2}) {
+		t.Fatalf("unexpected dropped counts %v (expected [2])", dropped)
+	}
+}
+
+func TestReaderSetReadDeadline(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{{Text: "finally", ChannelID: "C12345678"}},
+		start:    make(chan struct{}),
+	}
+
+	r := NewReader(client, "")
+	var readBytes [16]byte
+
+	if err := r.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatalf("unexpected SetReadDeadline error: %v", err)
+	}
+
+	_, err := r.Read(readBytes[:])
+	if err != ErrDeadlineExceeded {
+		t.Fatalf("unexpected Reader error %v (expected %v)", err, ErrDeadlineExceeded)
+	}
+	if terr, ok := err.(interface{ Timeout() bool }); !ok || !terr.Timeout() {
+		t.Fatalf("Reader error %v does not report a timeout", err)
+	}
+
+	if err := r.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("unexpected SetReadDeadline error: %v", err)
+	}
+
+	close(client.start)
+	n, err := r.Read(readBytes[:])
+	if err != nil {
+		t.Fatalf("unexpected Reader error: %v", err)
+	}
+	if out := string(readBytes[:n]); out != "finally\n" {
+		t.Fatalf("unexpected Reader output %q (expected %q)", out, "finally\n")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+}
+
+func TestReaderSetReadDeadlineRealClock(t *testing.T) {
+	// Deadlines follow the real clock, even when other timing is mocked.
+	timeNow = func() time.Time { return time.Now().Add(time.Hour) }
+	defer func() { timeNow = time.Now }()
+
+	client := &testReadClient{
+		messages: []Message{{Text: "on time", ChannelID: "C12345678"}},
+		start:    make(chan struct{}),
+	}
+
+	r := NewReader(client, "")
+	var readBytes [16]byte
+
+	if err := r.SetReadDeadline(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected SetReadDeadline error: %v", err)
+	}
+
+	close(client.start)
+	n, err := r.Read(readBytes[:])
+	if err != nil {
+		t.Fatalf("unexpected Reader error: %v", err)
+	}
+	if out := string(readBytes[:n]); out != "on time\n" {
+		t.Fatalf("unexpected Reader output %q (expected %q)", out, "on time\n")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+}
+
+func TestReaderTap(t *testing.T) {
+	client := &testReadClient{
+		messages: []Message{
+			{Text: "one", ChannelID: "C12345678"},
+			{Text: "two\r\nthree", ChannelID: "C12345678"},
+		},
+	}
+
+	var tap bytes.Buffer
+	r := NewReader(client, "", ReaderOptionTap(&tap))
+
+	actual := make([]byte, len("one\ntwo\nthree\n"))
+	if _, err := io.ReadFull(r, actual); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected Reader error: %q", err.Error())
+	}
+	client.wait()
+
+	if !bytes.Equal(tap.Bytes(), actual) {
+		t.Fatalf("unexpected tap contents %q (expected %q)", tap.Bytes(), actual)
+	}
+}
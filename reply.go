@@ -0,0 +1,49 @@
+package slackio
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrReplyTimeout is returned by SendAndAwaitReply when no matching reply
+// arrives within the timeout.
+var ErrReplyTimeout = errors.New("slackio: timed out waiting for reply")
+
+// ReadWriteClient represents objects that can both send slackio Messages and
+// allow subscription to a stream of them. Note that in slackio, Client
+// implements this interface.
+type ReadWriteClient interface {
+	ReadClient
+	WriteClient
+}
+
+// SendAndAwaitReply sends a message with the given text to a Slack channel,
+// then waits for a message in the same channel for which match returns true,
+// and returns it. If no such message arrives within timeout, ErrReplyTimeout is
+// returned. SendAndAwaitReply subscribes to client just before sending, so that
+// no reply can be missed; a matching message that arrives while the message is
+// still being sent is accepted as the reply, even if it was not a response.
+func SendAndAwaitReply(client ReadWriteClient, channelID, text string, match func(Message) bool, timeout time.Duration) (Message, error) {
+	msgCh := make(chan Message)
+	if err := client.Subscribe(msgCh); err != nil {
+		return Message{}, err
+	}
+	defer client.Unsubscribe(msgCh)
+
+	if err := client.SendMessage(Message{ChannelID: channelID, Text: text}); err != nil {
+		return Message{}, err
+	}
+
+	deadline := timeAfter(timeout)
+	for {
+		select {
+		case msg := <-msgCh:
+			if msg.ChannelID == channelID && match(msg) {
+				return msg, nil
+			}
+
+		case <-deadline:
+			return Message{}, ErrReplyTimeout
+		}
+	}
+}
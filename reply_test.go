@@ -0,0 +1,79 @@
+package slackio
+
+import (
+	"testing"
+	"time"
+)
+
+// testReplyClient responds to each sent message by delivering a predefined set
+// of replies to its subscriber.
+type testReplyClient struct {
+	ch      chan<- Message
+	sent    []Message
+	replies []Message
+}
+
+func (c *testReplyClient) Subscribe(ch chan<- Message) error {
+	c.ch = ch
+	return nil
+}
+
+func (c *testReplyClient) Unsubscribe(ch chan<- Message) error {
+	c.ch = nil
+	return nil
+}
+
+func (c *testReplyClient) SendMessage(m Message) error {
+	c.sent = append(c.sent, m)
+
+	ch, replies := c.ch, c.replies
+	go func() {
+		for _, reply := range replies {
+			ch <- reply
+		}
+	}()
+	return nil
+}
+
+func TestSendAndAwaitReply(t *testing.T) {
+	client := &testReplyClient{
+		replies: []Message{
+			{ChannelID: "C87654321", Text: "pong"},
+			{ChannelID: "C12345678", Text: "unrelated"},
+			{ChannelID: "C12345678", Text: "pong"},
+		},
+	}
+
+	reply, err := SendAndAwaitReply(client, "C12345678", "ping", func(m Message) bool {
+		return m.Text == "pong"
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected SendAndAwaitReply error: %v", err)
+	}
+
+	if reply.ChannelID != "C12345678" || reply.Text != "pong" {
+		t.Fatalf("unexpected reply %#v", reply)
+	}
+
+	if len(client.sent) != 1 || client.sent[0].Text != "ping" {
+		t.Fatalf("unexpected sent messages %#v", client.sent)
+	}
+
+	if client.ch != nil {
+		t.Fatal("SendAndAwaitReply did not unsubscribe")
+	}
+}
+
+func TestSendAndAwaitReplyTimeout(t *testing.T) {
+	timeCh := make(chan time.Time, 1)
+	timeAfter = func(_ time.Duration) <-chan time.Time { return timeCh }
+	defer func() { timeAfter = time.After }()
+
+	client := &testReplyClient{}
+	timeCh <- time.Now()
+
+	_, err := SendAndAwaitReply(client, "C12345678", "ping", func(Message) bool { return true }, time.Second)
+	if err != ErrReplyTimeout {
+		t.Fatalf("unexpected SendAndAwaitReply error %v (expected %v)", err, ErrReplyTimeout)
+	}
+}
@@ -0,0 +1,114 @@
+package slackio
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ClientOptionSendInterval causes a Client to wait for at least the given
+// interval between messages sent with SendMessage, to stay within Slack's rate
+// limits. Messages sent in the meantime are queued, and are delivered in order
+// of their Priority, highest first, and then in the order they were sent. Any
+// messages still queued when the Client is closed are discarded.
+func ClientOptionSendInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.sendInterval = d
+	}
+}
+
+// startSendQueue starts delivering messages from the Client's send queue, if
+// the Client is configured with a send interval.
+func (c *Client) startSendQueue() {
+	if c.sendInterval <= 0 {
+		return
+	}
+
+	c.sendReady = make(chan struct{}, 1)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.processSendQueue()
+	}()
+}
+
+// enqueueSend adds m to the Client's send queue.
+func (c *Client) enqueueSend(m Message) {
+	c.sendQueueLock.Lock()
+	heap.Push(&c.sendQueue, queuedMessage{m, c.sendQueueSeq})
+	c.sendQueueSeq++
+	c.sendQueueLock.Unlock()
+
+	select {
+	case c.sendReady <- struct{}{}:
+	default:
+	}
+}
+
+// processSendQueue delivers queued messages, waiting for the send interval
+// after each, until the Client is closed.
+func (c *Client) processSendQueue() {
+	for {
+		c.sendQueueLock.Lock()
+		var next *Message
+		if c.sendQueue.Len() > 0 {
+			m := heap.Pop(&c.sendQueue).(queuedMessage).msg
+			next = &m
+		}
+		c.sendQueueLock.Unlock()
+
+		if next == nil {
+			select {
+			case <-c.sendReady:
+				continue
+			case <-c.done:
+				return
+			}
+		}
+
+		c.deliver(*next)
+
+		select {
+		case <-timeAfter(c.sendInterval):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// queuedMessage is a Message in a Client's send queue. seq records the order in
+// which messages were queued, to keep delivery of equal priorities in order.
+type queuedMessage struct {
+	msg Message
+	seq int
+}
+
+// sendQueue is a priority queue of messages, implementing heap.Interface.
+type sendQueue []queuedMessage
+
+func (q sendQueue) Len() int {
+	return len(q)
+}
+
+func (q sendQueue) Less(i, j int) bool {
+	if q[i].msg.Priority != q[j].msg.Priority {
+		return q[i].msg.Priority > q[j].msg.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q sendQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *sendQueue) Push(x interface{}) {
+	*q = append(*q, x.(queuedMessage))
+}
+
+func (q *sendQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	old[len(old)-1] = queuedMessage{} // Allow for garbage collection
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,64 @@
+package slackio
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendInterval(t *testing.T) {
+	timers := make(chan chan time.Time, 1)
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		if d != time.Second {
+			t.Errorf("unexpected send interval %v (expected %v)", d, time.Second)
+		}
+
+		timer := make(chan time.Time, 1)
+		timers <- timer
+		return timer
+	}
+	defer func() { timeAfter = time.After }()
+
+	var logs bytes.Buffer
+	c := initClient(
+		ClientOptionSendInterval(time.Second),
+		ClientOptionDryRun(),
+		ClientOptionLogger(log.New(&logs, "", 0)),
+	)
+	defer c.Close()
+
+	send := func(text string, priority int) {
+		t.Helper()
+		if err := c.SendMessage(Message{ChannelID: "C12345678", Text: text, Priority: priority}); err != nil {
+			t.Fatalf("unexpected SendMessage error: %v", err)
+		}
+	}
+
+	// The first message is sent right away, and the rest are held until the
+	// interval elapses.
+	send("bulk 1", 0)
+	timer := <-timers
+
+	send("bulk 2", 0)
+	send("bulk 3", 0)
+	send("urgent 1", 10)
+	send("urgent 2", 10)
+
+	for i := 0; i < 4; i++ {
+		timer <- time.Now()
+		timer = <-timers
+	}
+
+	var texts []string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		texts = append(texts, line[strings.Index(line, `"`):])
+	}
+
+	expected := []string{`"bulk 1"`, `"urgent 1"`, `"urgent 2"`, `"bulk 2"`, `"bulk 3"`}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected send order %v (expected %v)", texts, expected)
+	}
+}
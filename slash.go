@@ -0,0 +1,57 @@
+package slackio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nlopes/slack"
+)
+
+// maxSlashCommandBytes limits the size of the slash command requests that a
+// handler from NewSlashCommandHandler will read.
+const maxSlashCommandBytes = 1 << 20
+
+// NewSlashCommandHandler returns an http.Handler that serves the requests that
+// Slack sends when a user invokes a slash command. Each request is verified
+// against the given signing secret from the Slack app's configuration, and
+// rejected with 401 Unauthorized if its signature or timestamp is invalid.
+// Otherwise, the command is passed to handler, and the text that it returns is
+// written as the immediate response, which Slack shows to the invoking user.
+func NewSlashCommandHandler(signingSecret string, handler func(slack.SlashCommand) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSlashCommandBytes))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+		if err == nil {
+			verifier.Write(body) // Always returns nil
+			err = verifier.Ensure()
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		// SlashCommandParse reads the form from the body that we just consumed.
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		cmd, err := slack.SlashCommandParse(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, handler(cmd))
+	})
+}
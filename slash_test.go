@@ -0,0 +1,117 @@
+package slackio
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// newSlashCommandRequest returns a slash command request with the given form
+// values, signed with secret at the given time.
+func newSlashCommandRequest(values url.Values, secret string, at time.Time) *http.Request {
+	body := values.Encode()
+	ts := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/slash", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestSlashCommandHandler(t *testing.T) {
+	var received []slack.SlashCommand
+	handler := NewSlashCommandHandler(testSigningSecret, func(cmd slack.SlashCommand) string {
+		received = append(received, cmd)
+		return "deploying " + cmd.Text
+	})
+
+	values := url.Values{
+		"command":    {"/deploy"},
+		"text":       {"api to production"},
+		"channel_id": {"C12345678"},
+		"user_id":    {"U12345678"},
+	}
+
+	cases := []struct {
+		description string
+		request     *http.Request
+		status      int
+		body        string
+	}{
+		{
+			description: "valid signature",
+			request:     newSlashCommandRequest(values, testSigningSecret, time.Now()),
+			status:      http.StatusOK,
+			body:        "deploying api to production",
+		},
+		{
+			description: "wrong secret",
+			request:     newSlashCommandRequest(values, "not the secret", time.Now()),
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "expired timestamp",
+			request:     newSlashCommandRequest(values, testSigningSecret, time.Now().Add(-time.Hour)),
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "missing signature",
+			request:     httptest.NewRequest(http.MethodPost, "/slash", strings.NewReader(values.Encode())),
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "wrong method",
+			request:     httptest.NewRequest(http.MethodGet, "/slash", nil),
+			status:      http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			received = nil
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tc.request)
+
+			if rec.Code != tc.status {
+				t.Fatalf("unexpected status %d (expected %d)", rec.Code, tc.status)
+			}
+
+			if tc.status != http.StatusOK {
+				if len(received) > 0 {
+					t.Fatalf("handler invoked for rejected request: %#v", received)
+				}
+				return
+			}
+
+			if body := rec.Body.String(); body != tc.body {
+				t.Fatalf("unexpected response body %q (expected %q)", body, tc.body)
+			}
+
+			if len(received) != 1 {
+				t.Fatalf("unexpected handler invocation count %d (expected 1)", len(received))
+			}
+
+			cmd := received[0]
+			if cmd.Command != "/deploy" || cmd.Text != "api to production" ||
+				cmd.ChannelID != "C12345678" || cmd.UserID != "U12345678" {
+				t.Fatalf("unexpected parsed command %#v", cmd)
+			}
+		})
+	}
+}
@@ -2,6 +2,47 @@ package slackio
 
 import "sync"
 
+// Subscription is a handle to a single subscription within a Client, as
+// returned by SubscribeWithHandle. It allows for control over message delivery
+// without unsubscribing.
+type Subscription struct {
+	sub *subscription
+}
+
+// Pause temporarily stops delivery of messages to the subscribed channel. The
+// subscription's position in the stream is preserved, and delivery will resume
+// from that position when Resume is called. Note that a subscription that is
+// paused for too long will be subject to the usual skip-forward behavior
+// described in the SubscribeAt documentation.
+func (s *Subscription) Pause() {
+	s.sub.pause()
+}
+
+// Resume continues delivery of messages to a paused subscription. Calling
+// Resume on a subscription that is not paused has no effect.
+func (s *Subscription) Resume() {
+	s.sub.resume()
+}
+
+// SubscriptionPolicy determines how a subscription behaves when its subscriber
+// falls behind the Client's overall message stream.
+type SubscriptionPolicy int
+
+const (
+	// PolicySkip causes a subscriber that falls behind the Client's message
+	// buffer to be skipped forward, as described in the SubscribeAt
+	// documentation. This is the default policy.
+	PolicySkip SubscriptionPolicy = iota
+
+	// PolicyBlock causes the Client to retain messages that a subscriber has yet
+	// to receive, growing its message buffer as necessary, so that no messages
+	// are lost. The buffer's growth is limited by ClientOptionMaxQueueSize. A
+	// slow subscriber using PolicyBlock holds back only the trimming of the
+	// buffer; it never delays the receipt of new messages by the Client, nor
+	// their delivery to other subscribers.
+	PolicyBlock
+)
+
 // subscription is an internal type that is tightly bound to Client and helps
 // simplify management tasks.
 type subscription struct {
@@ -10,14 +51,28 @@ type subscription struct {
 	ch     chan<- Message
 	done   chan struct{}
 	wg     sync.WaitGroup
+
+	// filter, if non-nil, selects the messages that are delivered to ch.
+	filter func(Message) bool
+
+	// idLock guards modifications to id by the process goroutine, which may
+	// read id freely. Other goroutines must read it using position.
+	idLock sync.Mutex
+
+	pauseLock sync.Mutex
+	pauseCh   chan struct{}
+	resumeCh  chan struct{}
 }
 
-func newSubscription(client *Client, id int, ch chan<- Message) *subscription {
+func newSubscription(client *Client, id int, ch chan<- Message, filter func(Message) bool) *subscription {
 	s := &subscription{
 		client: client,
 		id:     id,
 		ch:     ch,
 		done:   make(chan struct{}),
+		filter: filter,
+
+		pauseCh: make(chan struct{}, 1),
 	}
 
 	s.wg.Add(1)
@@ -46,6 +101,10 @@ func (s *subscription) process() {
 	// This function is designed to run in a goroutine (see newSubscription).
 
 	for s.active() {
+		if s.waitIfPaused() {
+			continue
+		}
+
 		s.client.messagesLock.RLock()
 
 		if len(s.client.messages) > 0 {
@@ -53,8 +112,10 @@ func (s *subscription) process() {
 			// queue. If so, this consumer has fallen way behind and we will skip
 			// them to the earliest message still in the queue. Message IDs will
 			// indicate that the skip happened.
+			skippedFrom := -1
 			if s.id < s.client.messages[0].ID {
-				s.id = s.client.messages[0].ID
+				skippedFrom = s.id
+				s.setID(s.client.messages[0].ID)
 			}
 
 			// Next, check if the message we are trying to get is in the queue right
@@ -66,12 +127,30 @@ func (s *subscription) process() {
 				msg := s.client.messages[idx]
 				s.client.messagesLock.RUnlock()
 
+				// Every subscriber receives the same queued Message, so give each its
+				// own copy of any reactions to keep them from affecting each other.
+				msg.Reactions = copyReactions(msg.Reactions)
+
+				// A skip always leaves us at a message in the queue, so this is the
+				// first point after one where the lock is released.
+				if skippedFrom >= 0 && s.client.onSubscriberLag != nil {
+					s.client.onSubscriberLag(skippedFrom, s.id)
+				}
+
+				if s.filter != nil && !s.filter(msg) {
+					s.setID(s.id + 1)
+					continue
+				}
+
+				// A pause that arrives while we are blocked on the send should leave
+				// the subscription at its current position.
 				select {
 				case s.ch <- msg:
+					s.setID(s.id + 1)
+				case <-s.pauseCh:
 				case <-s.done:
 				}
 
-				s.id++
 				continue
 			}
 		}
@@ -100,6 +179,67 @@ func (s *subscription) process() {
 	}
 }
 
+func (s *subscription) setID(id int) {
+	s.idLock.Lock()
+	defer s.idLock.Unlock()
+	s.id = id
+}
+
+// position returns the ID of the next message that the subscription will
+// attempt to deliver.
+func (s *subscription) position() int {
+	s.idLock.Lock()
+	defer s.idLock.Unlock()
+	return s.id
+}
+
+func (s *subscription) pause() {
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+
+	if s.resumeCh != nil {
+		return
+	}
+
+	s.resumeCh = make(chan struct{})
+
+	// Interrupt a blocked send if there is one. If not, the signal will be
+	// consumed harmlessly on a later send attempt.
+	select {
+	case s.pauseCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) resume() {
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+
+	if s.resumeCh != nil {
+		close(s.resumeCh)
+		s.resumeCh = nil
+	}
+}
+
+// waitIfPaused blocks while the subscription is paused, and returns true if it
+// did so.
+func (s *subscription) waitIfPaused() bool {
+	s.pauseLock.Lock()
+	resumeCh := s.resumeCh
+	s.pauseLock.Unlock()
+
+	if resumeCh == nil {
+		return false
+	}
+
+	select {
+	case <-resumeCh:
+	case <-s.done:
+	}
+
+	return true
+}
+
 func (s *subscription) stop() {
 	close(s.done)
 	s.wg.Wait()
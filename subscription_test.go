@@ -17,7 +17,7 @@ func TestBasicSubscription(t *testing.T) {
 
 	c := initClient()
 	ch := make(chan Message)
-	sub := newSubscription(c, 0, ch)
+	sub := newSubscription(c, 0, ch, nil)
 
 	// Try to help ensure that the subscriber goroutine gets to the point of
 	// waiting. This isn't perfect but should at least help.
@@ -53,7 +53,7 @@ func TestInPastSubscription(t *testing.T) {
 	c.distribute(&evt)
 
 	ch := make(chan Message)
-	sub := newSubscription(c, 0, ch)
+	sub := newSubscription(c, 0, ch, nil)
 
 	for i := 0; i < 3; i++ {
 		out := <-ch
@@ -91,7 +91,7 @@ func TestForwardSkippedSubscription(t *testing.T) {
 	c.distribute(&evt)
 
 	ch := make(chan Message)
-	sub := newSubscription(c, 0, ch)
+	sub := newSubscription(c, 0, ch, nil)
 
 	for i := 0; i < 3; i++ {
 		out := <-ch
@@ -104,3 +104,47 @@ func TestForwardSkippedSubscription(t *testing.T) {
 	sub.stop()
 	c.messagesCond.Broadcast()
 }
+
+func TestPausedSubscription(t *testing.T) {
+	msg := slack.Msg{Type: "message", Channel: "C12345678", Text: "hi"}
+	evt := slack.MessageEvent(slack.Message{Msg: msg})
+
+	// In this case, the subscription is paused while messages arrive. Nothing
+	// should be delivered until it is resumed, at which point delivery should
+	// pick up where it left off.
+
+	c := initClient()
+	defer c.Close()
+
+	ch := make(chan Message)
+	sub, err := c.SubscribeWithHandle(0, ch)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+
+	// Try to get the subscriber blocked on sending the first message, so that
+	// we can test interrupting it with the pause.
+	c.distribute(&evt)
+	time.Sleep(10 * time.Millisecond)
+
+	sub.Pause()
+	c.distribute(&evt)
+	c.distribute(&evt)
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case out := <-ch:
+		t.Fatalf("received message while paused: %#v", out)
+	default:
+	}
+
+	sub.Resume()
+
+	for i := 0; i < 3; i++ {
+		out := <-ch
+
+		if out.ID != i || out.Text != msg.Text {
+			t.Fatalf("unexpected message: %#v", out)
+		}
+	}
+}
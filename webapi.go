@@ -0,0 +1,385 @@
+package slackio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// webAPI represents the subset of the Slack Web API that Client relies on, for
+// operations that the real-time API does not support. It is implemented by
+// *slack.Client, and allows for mocking of Web API calls in tests.
+type webAPI interface {
+	DeleteMessage(channelID, ts string) (string, string, error)
+	GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error)
+	GetPermalink(*slack.PermalinkParameters) (string, error)
+	GetUserByEmail(email string) (*slack.User, error)
+	JoinConversation(channelID string) (*slack.Channel, string, []string, error)
+	OpenConversation(*slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	UnArchiveConversation(channelID string) error
+	UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	UploadFile(slack.FileUploadParameters) (*slack.File, error)
+}
+
+// ArchivedChannelError is returned by methods that send messages using Slack's
+// Web API when the destination channel has been archived. See
+// ClientOptionUnarchiveIfNeeded for a way to send to such channels anyway.
+//
+// Only Slack's is_archived error is reported this way. Slack also reports
+// channel_not_found for some archived channels, but the same error means that
+// the channel does not exist or is not visible to the token, and neither case
+// can be told apart from an archived channel or fixed by unarchiving. Such
+// errors are returned as Slack reported them.
+type ArchivedChannelError struct {
+	// ChannelID identifies the archived channel.
+	ChannelID string
+
+	// Err is the original error returned by Slack.
+	Err error
+}
+
+func (e *ArchivedChannelError) Error() string {
+	return "slackio: cannot send to archived channel " + e.ChannelID + " (" + e.Err.Error() + ")"
+}
+
+// Unwrap returns the original error returned by Slack.
+func (e *ArchivedChannelError) Unwrap() error {
+	return e.Err
+}
+
+// PostMessage sends the given Message to its associated Slack channel using
+// Slack's Web API. Unlike SendMessage, it waits for Slack to accept the message
+// and returns the timestamp that identifies it, which may be used to start a
+// thread.
+func (c *Client) PostMessage(m Message) (string, error) {
+	return c.postMessage(context.Background(), m)
+}
+
+// postMessage implements PostMessage, with a context for the Web API request
+// and any additional options to apply after those derived from the Message.
+func (c *Client) postMessage(ctx context.Context, m Message, extra ...slack.MsgOption) (string, error) {
+	if err := c.checkSend(m.ChannelID); err != nil {
+		return "", err
+	}
+
+	if c.dryRun {
+		c.logDryRun(m.ChannelID, m.Text)
+		return "", nil
+	}
+
+	username, iconEmoji := m.Username, m.IconEmoji
+	if username == "" {
+		username = c.defaultUsername
+	}
+	if iconEmoji == "" {
+		iconEmoji = c.defaultIconEmoji
+	}
+
+	// Slack only honors a custom identity for messages that are not sent as the
+	// authenticated user.
+	options := []slack.MsgOption{
+		slack.MsgOptionText(m.Text, false),
+		slack.MsgOptionAsUser(username == "" && iconEmoji == ""),
+	}
+
+	if username != "" {
+		options = append(options, slack.MsgOptionUsername(username))
+	}
+
+	if iconEmoji != "" {
+		options = append(options, slack.MsgOptionIconEmoji(iconEmoji))
+	}
+
+	if m.ThreadTimestamp != "" {
+		options = append(options, slack.MsgOptionTS(m.ThreadTimestamp))
+	}
+
+	if m.BroadcastReply {
+		options = append(options, slack.MsgOptionBroadcast())
+	}
+
+	if m.DisableMrkdwn {
+		options = append(options, slack.MsgOptionDisableMarkdown())
+	}
+
+	if m.Metadata != nil {
+		metadata, err := json.Marshal(m.Metadata)
+		if err != nil {
+			return "", err
+		}
+
+		options = append(options, c.msgOptionValues(func(v url.Values) {
+			v.Set("metadata", string(metadata))
+		}))
+	}
+
+	options = append(options, extra...)
+	_, ts, err := c.webAPIClient().PostMessageContext(ctx, m.ChannelID, options...)
+	if err != nil && err.Error() == "is_archived" {
+		ts, err = c.retryArchived(ctx, m.ChannelID, err, options)
+	}
+
+	if err == nil {
+		c.recordSent(ts)
+	}
+	return ts, err
+}
+
+// retryArchived handles an error from Slack indicating that a message could
+// not be posted to an archived channel. If the Client is configured to
+// unarchive channels, it does so and tries once more to post the message.
+// Otherwise, or if the channel cannot be unarchived, it returns an
+// *ArchivedChannelError.
+func (c *Client) retryArchived(ctx context.Context, channelID string, err error, options []slack.MsgOption) (string, error) {
+	archivedErr := &ArchivedChannelError{ChannelID: channelID, Err: err}
+	if !c.unarchiveIfNeeded {
+		return "", archivedErr
+	}
+
+	if err := c.webAPIClient().UnArchiveConversation(channelID); err != nil {
+		c.logf("slackio: failed to unarchive channel %s: %v", channelID, err)
+		return "", archivedErr
+	}
+
+	_, ts, err := c.webAPIClient().PostMessageContext(ctx, channelID, options...)
+	return ts, err
+}
+
+// SendMessageSync sends the given Message to its associated Slack channel,
+// and blocks until Slack has accepted it. Unlike SendMessage, which queues the
+// Message for delivery over the real-time API, SendMessageSync uses Slack's
+// Web API and returns any error encountered while sending. This is useful for
+// programs that send a message and then exit immediately.
+func (c *Client) SendMessageSync(m Message) error {
+	_, err := c.PostMessage(m)
+	return err
+}
+
+// SendMessageContext sends the given Message exactly as SendMessageSync does,
+// but uses the given context for the underlying Web API request. If the
+// context is canceled or times out before Slack accepts the Message, the
+// request is abandoned and the context's error (e.g. context.Canceled) is
+// returned.
+func (c *Client) SendMessageContext(ctx context.Context, m Message) error {
+	_, err := c.postMessage(ctx, m)
+	if err != nil && ctx.Err() != nil {
+		// The slack package reports an abandoned request as a *url.Error that
+		// merely wraps the context's error.
+		return ctx.Err()
+	}
+	return err
+}
+
+// ReplyInThread sends a message with the given text as a reply within the
+// thread of an incoming Message, using Slack's Web API. If the Message is
+// itself a reply, the new message joins the same thread. An error is returned
+// if the Message does not carry a Timestamp, as with a Message that was not
+// received from Slack.
+func (c *Client) ReplyInThread(to Message, text string) error {
+	threadTS := to.ThreadTimestamp
+	if threadTS == "" {
+		threadTS = to.Timestamp
+	}
+
+	if threadTS == "" {
+		return errors.New("slackio: cannot reply to a message without a timestamp")
+	}
+
+	_, err := c.postMessage(context.Background(), Message{
+		ChannelID:       to.ChannelID,
+		Text:            text,
+		ThreadTimestamp: threadTS,
+	})
+	return err
+}
+
+// SendTemporary sends a message with the given text to a Slack channel using
+// Slack's Web API, and deletes it once ttl has elapsed. This is useful for
+// transient status messages. An error is returned if the message cannot be
+// sent; a failure to delete it is only logged. Closing the Client cancels any
+// deletions that are still pending, leaving those messages in place, and waits
+// for any deletion already in progress to finish.
+func (c *Client) SendTemporary(channelID, text string, ttl time.Duration) error {
+	ts, err := c.postMessage(context.Background(), Message{ChannelID: channelID, Text: text})
+	if err != nil || ts == "" {
+		return err // A blank timestamp means we are in dry-run mode.
+	}
+
+	c.closeLock.Lock()
+	defer c.closeLock.Unlock()
+
+	if c.isClosed() {
+		return nil // The deletion is canceled, as if Close came just after.
+	}
+
+	expired := timeAfter(ttl)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		select {
+		case <-expired:
+		case <-c.done:
+			return
+		}
+
+		// If the Client closed just as the message expired, cancellation wins.
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		if _, _, err := c.webAPIClient().DeleteMessage(channelID, ts); err != nil {
+			c.logf("slackio: failed to delete temporary message %s in %s: %v", ts, channelID, err)
+		}
+	}()
+
+	return nil
+}
+
+// SendToEmail sends a message with the given text as a direct message to the
+// Slack user with the given email address, using Slack's Web API. The user's
+// direct message channel is looked up on first use and cached for the lifetime
+// of the Client.
+func (c *Client) SendToEmail(email, text string) error {
+	// Neither a closed Client nor a dry run should reach Slack, even to look up
+	// the user.
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	if c.dryRun {
+		c.logDryRun(email, text)
+		return nil
+	}
+
+	channelID, err := c.emailChannel(email)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.postMessage(context.Background(), Message{ChannelID: channelID, Text: text})
+	return err
+}
+
+// emailChannel returns the ID of the direct message channel with the Slack
+// user with the given email address, opening it if necessary.
+func (c *Client) emailChannel(email string) (string, error) {
+	c.emailChannelsLock.Lock()
+	channelID, ok := c.emailChannels[email]
+	c.emailChannelsLock.Unlock()
+
+	if ok {
+		return channelID, nil
+	}
+
+	user, err := c.webAPIClient().GetUserByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	ch, _, _, err := c.webAPIClient().OpenConversation(&slack.OpenConversationParameters{
+		Users: []string{user.ID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.emailChannelsLock.Lock()
+	c.emailChannels[email] = ch.ID
+	c.emailChannelsLock.Unlock()
+
+	return ch.ID, nil
+}
+
+// SendLinkButton sends a message to a Slack channel using Slack's Web API,
+// consisting of the given text accompanied by a single button that opens url
+// when clicked. The text is also used as the message's notification and
+// fallback text. For anything more elaborate, see Slack's Block Kit.
+func (c *Client) SendLinkButton(channelID, text, buttonText, url string) error {
+	button := slack.NewButtonBlockElement("", "", slack.NewTextBlockObject(slack.PlainTextType, buttonText, false, false))
+	button.URL = url
+
+	section := slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, text, false, false),
+		nil,
+		slack.NewAccessory(button),
+	)
+
+	_, err := c.postMessage(context.Background(), Message{ChannelID: channelID, Text: text}, slack.MsgOptionBlocks(section))
+	return err
+}
+
+// UploadSnippet uploads the given content to a Slack channel as a text
+// snippet, along with an optional initial comment. This is useful for content
+// that is too large to be sent comfortably as a normal message.
+func (c *Client) UploadSnippet(channelID, content, comment string) error {
+	if err := c.checkSend(channelID); err != nil {
+		return err
+	}
+
+	if c.dryRun {
+		c.logDryRun(channelID, content)
+		return nil
+	}
+
+	_, err := c.webAPIClient().UploadFile(slack.FileUploadParameters{
+		Content:        content,
+		Filetype:       "text",
+		Channels:       []string{channelID},
+		InitialComment: comment,
+	})
+	return err
+}
+
+// msgOptionValues returns a MsgOption that applies arbitrary updates to the
+// parameters of a chat.postMessage call. It supports parameters that the slack
+// package has no dedicated option for. The slack package only permits such
+// updates along with an explicit endpoint, so the option also directs the call
+// to chat.postMessage at this Client's API URL.
+func (c *Client) msgOptionValues(update func(url.Values)) slack.MsgOption {
+	apiURL := c.apiURL
+	if apiURL == "" {
+		apiURL = slack.APIURL
+	}
+	return slack.UnsafeMsgOptionEndpoint(apiURL+"chat.postMessage", update)
+}
+
+// ChannelInfo describes a single Slack channel.
+type ChannelInfo struct {
+	Name        string
+	Topic       string
+	Purpose     string
+	MemberCount int
+}
+
+// GetChannelInfo returns information about the Slack channel with the given
+// ID, using Slack's Web API.
+func (c *Client) GetChannelInfo(channelID string) (*ChannelInfo, error) {
+	ch, err := c.webAPIClient().GetConversationInfo(channelID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChannelInfo{
+		Name:        ch.Name,
+		Topic:       ch.Topic.Value,
+		Purpose:     ch.Purpose.Value,
+		MemberCount: ch.NumMembers,
+	}, nil
+}
+
+// JoinChannel joins the authenticated user to the Slack channel with the given
+// ID, using Slack's Web API, so that a Reader may receive its messages. Only
+// public channels may be joined this way; private channels require an
+// invitation, and Slack's error is returned for them.
+func (c *Client) JoinChannel(channelID string) error {
+	_, _, _, err := c.webAPIClient().JoinConversation(channelID)
+	return err
+}
@@ -3,13 +3,148 @@ package slackio
 import (
 	"errors"
 	"io"
+	"strings"
 	"sync"
+	"text/template"
 )
 
 // WriteClient represents objects that can send slackio Messages. Note that in
 // slackio, Client implements this interface.
 type WriteClient interface {
-	SendMessage(Message)
+	SendMessage(Message) error
+}
+
+// PostClient represents objects that can send slackio Messages and report the
+// timestamp that Slack assigns to each one. Note that in slackio, Client
+// implements this interface.
+type PostClient interface {
+	PostMessage(Message) (string, error)
+}
+
+// SnippetClient represents objects that can upload text snippets to Slack.
+// Note that in slackio, Client implements this interface.
+type SnippetClient interface {
+	UploadSnippet(channelID, content, comment string) error
+}
+
+// WriterOption configures optional behavior for a Writer.
+type WriterOption func(*Writer)
+
+// WriterOptionSnippets causes a Writer to upload any batch longer than maxBytes
+// as a text snippet using the given client, rather than sending it as a normal
+// message. Each snippet is posted with the given initial comment, which may be
+// blank. Errors from the upload are returned when the Writer is closed.
+func WriterOptionSnippets(client SnippetClient, maxBytes int, comment string) WriterOption {
+	return func(w *Writer) {
+		w.snippetClient = client
+		w.snippetMaxBytes = maxBytes
+		w.snippetComment = comment
+	}
+}
+
+// WriterOptionSnippetMaxLines causes a Writer using WriterOptionSnippets to
+// also upload any batch with more than maxLines lines as a snippet, even if it
+// is no longer than the maximum number of bytes. Multi-line output is often
+// more readable as a snippet regardless of its size. It has no effect without
+// WriterOptionSnippets.
+func WriterOptionSnippetMaxLines(maxLines int) WriterOption {
+	return func(w *Writer) {
+		w.snippetMaxLines = maxLines
+	}
+}
+
+// WriterOptionDisableMrkdwn causes a Writer to send its output using the given
+// client's PostMessage method, with Slack's mrkdwn formatting disabled. This is
+// useful for output like code or logs, where characters such as asterisks and
+// underscores should be displayed literally.
+func WriterOptionDisableMrkdwn(client PostClient) WriterOption {
+	return func(w *Writer) {
+		w.postClient = client
+		w.disableMrkdwn = true
+	}
+}
+
+// WriterOptionBroadcastReplies causes a Writer created with NewThreadWriter to
+// send each of its replies to the main body of the channel as well as to the
+// thread, as with Slack's "Also send to channel" option. NewWriter panics if
+// this option is used for a Writer that does not write to a thread.
+func WriterOptionBroadcastReplies() WriterOption {
+	return func(w *Writer) {
+		w.broadcastReplies = true
+	}
+}
+
+// WriterOptionBuffered causes a Writer to accept each Write into an in-memory
+// buffer and return immediately, rather than waiting for its Batcher to
+// consume the data. This prevents a slow Batcher or Slack connection from
+// blocking the caller's goroutine, at the cost of unbounded memory usage if
+// output is produced faster than it can be sent.
+func WriterOptionBuffered() WriterOption {
+	return func(w *Writer) {
+		w.buffered = true
+	}
+}
+
+// WriterOptionMarkdown causes a Writer to convert each batch of its output from
+// standard Markdown to Slack's mrkdwn dialect before sending it, using
+// ConvertMarkdown.
+func WriterOptionMarkdown() WriterOption {
+	return func(w *Writer) {
+		w.markdown = true
+	}
+}
+
+// WriterOptionSanitizeUTF8 causes a Writer to replace each run of invalid UTF-8
+// bytes in its output with the given replacement string before sending it, so
+// that binary or improperly encoded output does not cause Slack to reject or
+// mangle a message. A replacement of "\uFFFD" (the Unicode replacement
+// character) makes the substitutions visible, while a blank replacement drops
+// the invalid bytes entirely.
+func WriterOptionSanitizeUTF8(replacement string) WriterOption {
+	return func(w *Writer) {
+		w.sanitizeUTF8 = true
+		w.utf8Replacement = replacement
+	}
+}
+
+// WriterOptionRecover causes a Writer to recover from any panic in its internal
+// goroutine, such as one raised by its client while sending a batch, and pass
+// the panic's value to the given handler instead of crashing the program. The
+// batch that caused the panic is dropped. Panics in the Writer's Batcher, which
+// runs in goroutines of its own, cannot be recovered. By default, such panics
+// are not recovered.
+func WriterOptionRecover(handler func(interface{})) WriterOption {
+	return func(w *Writer) {
+		w.recoverHandler = handler
+	}
+}
+
+// WriterOptionTap causes a Writer to write a copy of all bytes that it accepts
+// from Write to the given io.Writer, which is useful for debugging the
+// formatting of output before it is batched. Errors from tap are ignored so
+// that they do not interrupt writing.
+func WriterOptionTap(tap io.Writer) WriterOption {
+	return func(w *Writer) {
+		w.tap = tap
+	}
+}
+
+// WriterOptionWordWrap causes a Writer to reformat any batch longer than
+// maxBytes bytes, which Slack would display as an unwieldy wall of text. Each
+// line of such a batch that exceeds width characters is wrapped at word
+// boundaries where possible, keeping its indentation, while shorter lines are
+// left as they are. The result is then split into as many messages of at most
+// maxBytes bytes as necessary, breaking only between lines (as by
+// NewByteSizeBatcher). Words longer than width are left intact. Both maxBytes
+// and width must be positive, or WriterOptionWordWrap will panic.
+func WriterOptionWordWrap(maxBytes, width int) WriterOption {
+	if maxBytes <= 0 || width <= 0 {
+		panic(errors.New("slackio: WriterOptionWordWrap requires a positive maxBytes and width"))
+	}
+
+	return func(w *Writer) {
+		w.batcher = newWordWrapBatcher(w.batcher, maxBytes, width)
+	}
 }
 
 // Writer writes messages to the main body of a single Slack channel.
@@ -18,14 +153,50 @@ type Writer struct {
 	channelID string
 	batcher   Batcher
 	wg        sync.WaitGroup
-	writeOut  io.ReadCloser
 	writeIn   io.WriteCloser
 	writeErr  error
+
+	// writeLock guards writeIn against replacement by Sync, and ensures that
+	// writes are not interleaved with a Sync or Close.
+	writeLock sync.Mutex
+	closed    bool
+
+	snippetClient   SnippetClient
+	snippetMaxBytes int
+	snippetMaxLines int
+	snippetComment  string
+
+	postClient       PostClient
+	threadTS         string
+	joinThread       bool
+	broadcastReplies bool
+	disableMrkdwn    bool
+
+	template *template.Template
+	markdown bool
+	buffered bool
+
+	sanitizeUTF8    bool
+	utf8Replacement string
+
+	// rotation, if non-nil, holds the channels that successive batches are sent
+	// to in turn, in place of channelID.
+	rotation     []string
+	nextRotation int
+
+	recoverHandler func(interface{})
+
+	tap io.Writer
+
+	// channelLock, if non-nil, is held while each Write is sent in full (see
+	// ClientOptionSerializeWrites).
+	channelLock *sync.Mutex
 }
 
 // NewWriter returns a new Writer. channelID must be non-blank, or NewWriter
 // will panic. If batcher is nil, DefaultBatcher will be used as the Batcher.
-func NewWriter(client WriteClient, channelID string, batcher Batcher) *Writer {
+// Any provided options are applied in order.
+func NewWriter(client WriteClient, channelID string, batcher Batcher, opts ...WriterOption) *Writer {
 	if channelID == "" {
 		panic(errors.New("slackio: Writer's channelID cannot be blank"))
 	}
@@ -40,37 +211,411 @@ func NewWriter(client WriteClient, channelID string, batcher Batcher) *Writer {
 		batcher:   batcher,
 	}
 
-	c.writeOut, c.writeIn = io.Pipe()
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.broadcastReplies && !c.joinThread {
+		panic(errors.New("slackio: WriterOptionBroadcastReplies requires a thread Writer"))
+	}
+
+	c.start()
+
+	if client, ok := client.(*Client); ok {
+		client.registerWriter(c)
+		if client.serializeWrites && c.rotation == nil {
+			c.channelLock = client.channelLock(channelID)
+		}
+	}
+	return c
+}
+
+// start connects a new pipe to a new instance of the Writer's Batcher, and
+// begins sending its output to Slack.
+func (c *Writer) start() {
+	writeOut, writeIn := io.Pipe()
+	c.writeIn = writeIn
+	if c.buffered {
+		c.writeIn = newBufferedWriter(writeIn)
+	}
 
 	// Process outgoing writes to Slack
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		batchCh, errCh := c.batcher(c.writeOut)
+		batchCh, errCh := c.batcher(writeOut)
 
 		for batch := range batchCh {
-			c.client.SendMessage(Message{
-				ChannelID: c.channelID,
-				Text:      batch,
-			})
+			if err := c.sendRecovered(batch); err != nil && c.writeErr == nil {
+				c.writeErr = err
+			}
 		}
 
-		c.writeErr = <-errCh
+		// An error from the Batcher takes precedence over any send error.
+		if err := <-errCh; err != nil {
+			c.writeErr = err
+		}
+
+		// A Batcher may stop reading before the end of its input, such as when
+		// LineBatcher encounters a line that is too long. Closing the read half
+		// releases any Write that is still blocked, so that it cannot hold
+		// writeLock forever.
+		writeOut.CloseWithError(io.ErrClosedPipe)
 	}()
+}
 
-	return c
+// NewThreadWriter returns a new Writer that sends all of its output as replies
+// within the thread identified by threadTS, using DefaultBatcher as the
+// Batcher. If threadTS is blank, the first batch of output is sent to the main
+// body of the channel, and all subsequent output is sent as replies in the
+// thread that it starts. Any provided options are applied in order.
+func NewThreadWriter(client *Client, channelID, threadTS string, opts ...WriterOption) *Writer {
+	thread := func(w *Writer) {
+		w.postClient = client
+		w.threadTS = threadTS
+		w.joinThread = true
+	}
+
+	return NewWriter(client, channelID, nil, append([]WriterOption{thread}, opts...)...)
+}
+
+// NewTemplateWriter returns a new Writer that executes tmpl for each line of
+// its output, and sends the result in place of the original line. The template
+// receives the line as a string, without its trailing newline. If execution of
+// the template fails, the line is not sent, and the first such error is returned
+// when the Writer is closed.
+func NewTemplateWriter(client WriteClient, channelID string, tmpl *template.Template) *Writer {
+	return NewWriter(client, channelID, LineBatcher, func(w *Writer) {
+		w.template = tmpl
+	})
+}
+
+// NewRoundRobinWriter returns a new Writer that sends each batch of its output
+// to the next of the given channels in turn, starting over with the first after
+// the last. This spreads high-volume output across multiple channels, so that
+// no single channel's rate limit becomes a bottleneck. channelIDs must contain
+// at least one channel, and none may be blank, or NewRoundRobinWriter will
+// panic. If batcher is nil, DefaultBatcher will be used as the Batcher.
+func NewRoundRobinWriter(client WriteClient, channelIDs []string, batcher Batcher) *Writer {
+	if len(channelIDs) == 0 {
+		panic(errors.New("slackio: Writer's channelIDs cannot be empty"))
+	}
+
+	for _, id := range channelIDs {
+		if id == "" {
+			panic(errors.New("slackio: Writer's channelIDs cannot be blank"))
+		}
+	}
+
+	rotation := append([]string(nil), channelIDs...)
+	return NewWriter(client, rotation[0], batcher, func(w *Writer) {
+		w.rotation = rotation
+	})
+}
+
+// nextChannelID returns the channel that the next batch should be sent to.
+func (c *Writer) nextChannelID() string {
+	if c.rotation == nil {
+		return c.channelID
+	}
+
+	id := c.rotation[c.nextRotation]
+	c.nextRotation = (c.nextRotation + 1) % len(c.rotation)
+	return id
+}
+
+// sendRecovered calls send, and recovers from any panic that occurs if the
+// Writer has a recover handler.
+func (c *Writer) sendRecovered(batch string) error {
+	if c.recoverHandler != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				c.recoverHandler(v)
+			}
+		}()
+	}
+
+	return c.send(batch)
+}
+
+// send delivers a single batch to Slack, as either a message or a snippet.
+// Batches that are empty or consist only of whitespace, such as those that
+// LineBatcher emits for blank lines, are dropped, since Slack would reject
+// them.
+func (c *Writer) send(batch string) error {
+	batch, ok, err := c.prepare(batch)
+	if !ok || err != nil {
+		return err
+	}
+
+	channelID := c.nextChannelID()
+
+	if c.snippetClient != nil && c.needsSnippet(batch) {
+		return c.snippetClient.UploadSnippet(channelID, batch, c.snippetComment)
+	}
+
+	msg := Message{
+		ChannelID:       channelID,
+		Text:            batch,
+		ThreadTimestamp: c.threadTS,
+		BroadcastReply:  c.broadcastReplies && c.threadTS != "",
+		DisableMrkdwn:   c.disableMrkdwn,
+	}
+
+	if c.postClient == nil {
+		return c.client.SendMessage(msg)
+	}
+
+	ts, err := c.postClient.PostMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	if c.joinThread && c.threadTS == "" {
+		c.threadTS = ts
+	}
+
+	return nil
+}
+
+// prepare applies the Writer's formatting to a batch before it is sent, and
+// reports whether the batch should be sent at all.
+func (c *Writer) prepare(batch string) (string, bool, error) {
+	if c.sanitizeUTF8 {
+		batch = strings.ToValidUTF8(batch, c.utf8Replacement)
+	}
+
+	if strings.TrimSpace(batch) == "" {
+		return "", false, nil
+	}
+
+	if c.template != nil {
+		var out strings.Builder
+		if err := c.template.Execute(&out, batch); err != nil {
+			return "", false, err
+		}
+		batch = out.String()
+	}
+
+	if c.markdown {
+		batch = ConvertMarkdown(batch)
+	}
+
+	return batch, true, nil
+}
+
+// needsSnippet reports whether batch exceeds the limits for sending it as a
+// normal message rather than a snippet.
+func (c *Writer) needsSnippet(batch string) bool {
+	if len(batch) > c.snippetMaxBytes {
+		return true
+	}
+
+	lines := strings.Count(strings.TrimSuffix(batch, "\n"), "\n") + 1
+	return c.snippetMaxLines > 0 && lines > c.snippetMaxLines
 }
 
 // Write submits text to the main body of a Slack channel, with message
-// boundaries determined by the Writer's Batcher.
+// boundaries determined by the Writer's Batcher. By default, Write blocks until
+// the Batcher has consumed all of p, and so returns either len(p) or an error.
+// See WriterOptionBuffered for a way to avoid this blocking.
 func (c *Writer) Write(p []byte) (int, error) {
-	return c.writeIn.Write(p)
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if c.channelLock != nil {
+		c.channelLock.Lock()
+		defer c.channelLock.Unlock()
+	}
+
+	n, err := c.writeIn.Write(p)
+	if n > 0 && c.tap != nil {
+		c.tap.Write(p[:n])
+	}
+
+	// Any error in sending will be reported by Close, as usual.
+	if c.channelLock != nil && !c.closed {
+		c.flush()
+	}
+	return n, err
+}
+
+// WriteTo sends p to the main body of the Slack channel identified by
+// channelID, rather than the Writer's own channel, for this write only. Unlike
+// Write, WriteTo bypasses the Writer's Batcher and sends all of p as a single
+// message (or snippet) before returning, with the same formatting as any other
+// batch. As a result, output from earlier calls to Write that the Batcher has
+// not yet emitted may be sent after p; call Sync first if this order matters.
+// With ClientOptionSerializeWrites, p is sent while holding the lock for
+// channelID, as any Writer for that channel would.
+func (c *Writer) WriteTo(channelID string, p []byte) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if client, ok := c.client.(*Client); ok && client.serializeWrites {
+		lock := client.channelLock(channelID)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	if c.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if c.tap != nil {
+		c.tap.Write(p)
+	}
+
+	batch, ok, err := c.prepare(string(p))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return len(p), nil
+	}
+
+	if c.snippetClient != nil && c.needsSnippet(batch) {
+		err = c.snippetClient.UploadSnippet(channelID, batch, c.snippetComment)
+	} else {
+		msg := Message{
+			ChannelID:     channelID,
+			Text:          batch,
+			DisableMrkdwn: c.disableMrkdwn,
+		}
+
+		if c.postClient == nil {
+			err = c.client.SendMessage(msg)
+		} else {
+			_, err = c.postClient.PostMessage(msg)
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync blocks until all data previously written to this Writer has been
+// emitted by its Batcher and sent to Slack, without closing the Writer. The
+// Batcher sees the end of its input just as it would on Close, so an incomplete
+// line of output will be sent as if it were complete. Sync returns the same
+// error that Close would return at this point, or io.ErrClosedPipe if the
+// Writer is already closed.
+//
+// Note that for a WriteClient like Client that sends messages asynchronously,
+// Sync guarantees only that each send was issued.
+func (c *Writer) Sync() error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+
+	return c.flush()
+}
+
+// flush implements Sync. It must be called with writeLock held.
+func (c *Writer) flush() error {
+	c.writeIn.Close() // Always returns nil
+	c.wg.Wait()
+
+	// Writes after this point go to a fresh instance of the Batcher.
+	err := c.writeErr
+	c.start()
+	return err
 }
 
 // Close disconnects this Writer from Slack and shuts down internal buffers.
-// After calling Close, the next call to Write will result in an error.
+// After calling Close, the next call to Write will result in an error. Close
+// returns any error from the Writer's Batcher, or else the first error
+// encountered while sending output to Slack.
 func (c *Writer) Close() error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.closed = true
 	c.writeIn.Close() // Always returns nil
 	c.wg.Wait()
+
+	if client, ok := c.client.(*Client); ok {
+		client.unregisterWriter(c)
+	}
 	return c.writeErr
 }
+
+// bufferedWriter accepts writes into an unbounded in-memory buffer, and copies
+// them to an underlying io.WriteCloser from a separate goroutine.
+type bufferedWriter struct {
+	w    io.WriteCloser
+	done chan struct{}
+
+	lock   sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	closed bool
+}
+
+func newBufferedWriter(w io.WriteCloser) *bufferedWriter {
+	b := &bufferedWriter{
+		w:    w,
+		done: make(chan struct{}),
+	}
+	b.cond = sync.NewCond(&b.lock)
+
+	go b.flush()
+	return b
+}
+
+// flush copies buffered data to the underlying io.WriteCloser until the
+// bufferedWriter is closed and its buffer is empty, then closes it.
+func (b *bufferedWriter) flush() {
+	defer close(b.done)
+	defer b.w.Close()
+
+	for {
+		b.lock.Lock()
+		for len(b.buf) == 0 && !b.closed {
+			b.cond.Wait()
+		}
+
+		data := b.buf
+		b.buf = nil
+		b.lock.Unlock()
+
+		if len(data) == 0 {
+			return
+		}
+
+		// Writes to the pipe block until the Batcher reads them, and fail once the
+		// Writer closes the pipe's read half because the Batcher has stopped. The
+		// Writer reports the Batcher's error, so the data is simply dropped.
+		b.w.Write(data)
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	b.buf = append(b.buf, p...)
+	b.cond.Signal()
+	return len(p), nil
+}
+
+// Close waits for all buffered data to be flushed, then closes the underlying
+// io.WriteCloser.
+func (b *bufferedWriter) Close() error {
+	b.lock.Lock()
+	b.closed = true
+	b.cond.Signal()
+	b.lock.Unlock()
+
+	<-b.done
+	return nil
+}
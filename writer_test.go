@@ -1,11 +1,19 @@
 package slackio
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"log"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"text/template"
+	"time"
+	"unicode/utf8"
 )
 
 func mockStaticBatcher(r io.Reader) (<-chan string, <-chan error) {
@@ -56,10 +64,11 @@ type testWriteClient struct {
 	gotMessage chan struct{}
 }
 
-func (c *testWriteClient) SendMessage(m Message) {
+func (c *testWriteClient) SendMessage(m Message) error {
 	c.initOnce.Do(func() { c.gotMessage = make(chan struct{}) })
 	c.lastMessage = m
 	c.gotMessage <- struct{}{}
+	return nil
 }
 
 func (c *testWriteClient) wait() {
@@ -119,3 +128,770 @@ func TestWriterCloseReturnsBatcherError(t *testing.T) {
 		t.Fatalf("Writer returned unexpected error on Close: %q", err.Error())
 	}
 }
+
+type testSnippetClient struct {
+	snippets []string
+	comments []string
+}
+
+func (c *testSnippetClient) UploadSnippet(channelID, content, comment string) error {
+	c.snippets = append(c.snippets, content)
+	c.comments = append(c.comments, comment)
+	return nil
+}
+
+func TestWriterSnippets(t *testing.T) {
+	client := &testWriteClient{}
+	snippets := &testSnippetClient{}
+	w := NewWriter(client, "C12345678", LineBatcher, WriterOptionSnippets(snippets, 10, "(output)"))
+
+	if _, err := w.Write([]byte("short\nthis line is too long\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	client.wait()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if client.lastMessage.Text != "short" {
+		t.Fatalf("unexpected message text %q (expected %q)", client.lastMessage.Text, "short")
+	}
+
+	expected := []string{"this line is too long"}
+	if !reflect.DeepEqual(snippets.snippets, expected) {
+		t.Fatalf("unexpected snippets %#v (expected %#v)", snippets.snippets, expected)
+	}
+
+	if snippets.comments[0] != "(output)" {
+		t.Fatalf("unexpected snippet comment %q (expected %q)", snippets.comments[0], "(output)")
+	}
+}
+
+func TestWriterSnippetMaxLines(t *testing.T) {
+	client := &recordingWriteClient{}
+	snippets := &testSnippetClient{}
+
+	// With this interval, each Sync flushes exactly one batch.
+	batcher := NewIntervalBatcher(LineBatcher, time.Hour, "\n")
+	w := NewWriter(client, "C12345678", batcher,
+		WriterOptionSnippets(snippets, 1000, ""),
+		WriterOptionSnippetMaxLines(3),
+	)
+
+	for _, output := range []string{"1\n2\n3\n", "1\n2\n3\n4\n"} {
+		if _, err := w.Write([]byte(output)); err != nil {
+			t.Fatalf("unexpected Writer error: %q", err.Error())
+		}
+
+		if err := w.Sync(); err != nil {
+			t.Fatalf("unexpected Writer error on sync: %q", err.Error())
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if texts := client.texts(); !reflect.DeepEqual(texts, []string{"1\n2\n3"}) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, []string{"1\n2\n3"})
+	}
+
+	expected := []string{"1\n2\n3\n4"}
+	if !reflect.DeepEqual(snippets.snippets, expected) {
+		t.Fatalf("unexpected snippets %#v (expected %#v)", snippets.snippets, expected)
+	}
+}
+
+func TestWriterWordWrap(t *testing.T) {
+	client := &recordingWriteClient{}
+	w := NewWriter(client, "C12345678", LineBatcher, WriterOptionWordWrap(40, 20))
+
+	paragraph := "The quick brown fox jumps over the lazy dog, " +
+		"and then the extraordinarily energetic fox jumps right back again."
+	for _, output := range []string{"short line\n", paragraph + "\n"} {
+		if _, err := w.Write([]byte(output)); err != nil {
+			t.Fatalf("unexpected Writer error: %q", err.Error())
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []string{
+		"short line",
+		"The quick brown fox\njumps over the lazy",
+		"dog, and then the\nextraordinarily",
+		"energetic fox jumps\nright back again.",
+	}
+	if texts := client.texts(); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, expected)
+	}
+
+	var words []string
+	for _, text := range expected[1:] {
+		for _, line := range strings.Split(text, "\n") {
+			if len(line) > 20 {
+				t.Errorf("line %q exceeds the target width", line)
+			}
+		}
+		words = append(words, strings.Fields(text)...)
+	}
+	if rejoined := strings.Join(words, " "); rejoined != paragraph {
+		t.Fatalf("wrapping changed the words of the paragraph: %q", rejoined)
+	}
+}
+
+func TestClientFlushAll(t *testing.T) {
+	var logs bytes.Buffer
+	c := initClient(ClientOptionDryRun(), ClientOptionLogger(log.New(&logs, "", 0)))
+	defer c.Close()
+
+	// With this interval, output is only sent when the Writer is flushed.
+	batcher := NewIntervalBatcher(LineBatcher, time.Hour, "\n")
+	w1 := NewWriter(c, "C11111111", batcher)
+	w2 := NewWriter(c, "C22222222", batcher)
+
+	for _, write := range []struct {
+		w    *Writer
+		text string
+	}{{w1, "one\n"}, {w2, "two\n"}} {
+		if _, err := write.w.Write([]byte(write.text)); err != nil {
+			t.Fatalf("unexpected Writer error: %q", err.Error())
+		}
+	}
+
+	if err := c.FlushAll(); err != nil {
+		t.Fatalf("unexpected FlushAll error: %v", err)
+	}
+
+	for _, expected := range []string{`C11111111: "one"`, `C22222222: "two"`} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("FlushAll did not send %s; got:\n%s", expected, logs.String())
+		}
+	}
+
+	if err := w1.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+	if err := c.FlushAll(); err != nil {
+		t.Fatalf("unexpected FlushAll error with a closed Writer: %v", err)
+	}
+	if err := w2.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if n := len(c.writers); n != 0 {
+		t.Fatalf("%d closed Writers remain registered with the Client", n)
+	}
+}
+
+func TestWriterSerializeWrites(t *testing.T) {
+	var logs bytes.Buffer
+	c := initClient(
+		ClientOptionSerializeWrites(),
+		ClientOptionDryRun(),
+		ClientOptionLogger(log.New(&logs, "", 0)),
+	)
+	defer c.Close()
+
+	const blocks = 20
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		w := NewWriter(c, "C12345678", LineBatcher)
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for i := 0; i < blocks; i++ {
+				if _, err := w.Write([]byte(name + "1\n" + name + "2\n" + name + "3\n")); err != nil {
+					t.Errorf("unexpected Writer error: %q", err.Error())
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("unexpected Writer error on close: %q", err.Error())
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2*3*blocks {
+		t.Fatalf("unexpected number of sends %d (expected %d)", len(lines), 2*3*blocks)
+	}
+
+	for i := 0; i < len(lines); i += 3 {
+		name := lines[i][len(lines[i])-3 : len(lines[i])-2]
+		for j := 0; j < 3; j++ {
+			expected := fmt.Sprintf("%q", fmt.Sprintf("%s%d", name, j+1))
+			if !strings.HasSuffix(lines[i+j], expected) {
+				t.Fatalf("block interleaved at send %d: %q (expected %s)", i+j, lines[i+j], expected)
+			}
+		}
+	}
+}
+
+func TestWriterBatcherStopsReading(t *testing.T) {
+	w := NewWriter(&recordingWriteClient{}, "C12345678", LineBatcher)
+
+	// LineBatcher gives up on a line longer than its 64 KiB buffer, and stops
+	// reading from the Writer while Write is still blocked.
+	line := bytes.Repeat([]byte("x"), 128*1024)
+
+	done := make(chan error, 2)
+	go func() {
+		_, err := w.Write(line)
+		done <- err
+		done <- w.Close()
+	}()
+
+	for _, expected := range []error{io.ErrClosedPipe, bufio.ErrTooLong} {
+		select {
+		case err := <-done:
+			if err != expected {
+				t.Fatalf("unexpected Writer error %v (expected %v)", err, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Writer hung after its Batcher stopped reading")
+		}
+	}
+}
+
+func TestBufferedWriterBatcherStopsReading(t *testing.T) {
+	w := NewWriter(&recordingWriteClient{}, "C12345678", LineBatcher, WriterOptionBuffered())
+
+	// As in TestWriterBatcherStopsReading, the Batcher stops reading partway
+	// through this line, but the buffered Write does not wait to find out.
+	line := bytes.Repeat([]byte("x"), 128*1024)
+	if _, err := w.Write(line); err != nil {
+		t.Fatalf("unexpected Writer error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Close() }()
+
+	select {
+	case err := <-done:
+		if err != bufio.ErrTooLong {
+			t.Fatalf("unexpected Writer error on close %v (expected %v)", err, bufio.ErrTooLong)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("buffered Writer hung on close after its Batcher stopped reading")
+	}
+}
+
+func TestThreadWriter(t *testing.T) {
+	cases := []struct {
+		description string
+		threadTS    string
+		expected    []string
+	}{
+		{
+			description: "replies to an existing thread",
+			threadTS:    "1111.2222",
+			expected:    []string{"1111.2222", "1111.2222", "1111.2222"},
+		},
+		{
+			description: "starts a new thread with the first message",
+			threadTS:    "",
+			expected:    []string{"", "1234.0001", "1234.0001"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			timeCh := make(chan time.Time)
+			timeAfter = func(_ time.Duration) <-chan time.Time { return timeCh }
+			defer func() { timeAfter = time.After }()
+
+			api := &testWebAPI{}
+			c := initClient()
+			c.api = api
+
+			w := NewThreadWriter(c, "C12345678", tc.threadTS)
+			for _, line := range []string{"one\n", "two\n", "three\n"} {
+				if _, err := w.Write([]byte(line)); err != nil {
+					t.Fatalf("unexpected Writer error: %q", err.Error())
+				}
+				timeCh <- time.Now()
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected Writer error on close: %q", err.Error())
+			}
+
+			var actual []string
+			for _, post := range api.posts {
+				actual = append(actual, post.values.Get("thread_ts"))
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Fatalf("unexpected thread timestamps %#v (expected %#v)", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestThreadWriterBroadcastReplies(t *testing.T) {
+	timeCh := make(chan time.Time)
+	timeAfter = func(_ time.Duration) <-chan time.Time { return timeCh }
+	defer func() { timeAfter = time.After }()
+
+	api := &testWebAPI{}
+	c := initClient()
+	c.api = api
+
+	w := NewThreadWriter(c, "C12345678", "1111.2222", WriterOptionBroadcastReplies())
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("unexpected Writer error: %q", err.Error())
+		}
+		timeCh <- time.Now()
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if len(api.posts) != 3 {
+		t.Fatalf("unexpected number of posts %d (expected 3)", len(api.posts))
+	}
+
+	for i, post := range api.posts {
+		if broadcast := post.values.Get("reply_broadcast"); broadcast != "true" {
+			t.Errorf("post %d has reply_broadcast %q (expected \"true\")", i, broadcast)
+		}
+	}
+}
+
+func TestBroadcastRepliesRequiresThread(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewWriter did not panic without a thread target")
+		}
+	}()
+
+	NewWriter(&recordingWriteClient{}, "C12345678", nil, WriterOptionBroadcastReplies())
+}
+
+func TestWriterWriteTo(t *testing.T) {
+	client := &recordingWriteClient{}
+	w := NewWriter(client, "C12345678", LineBatcher)
+
+	if _, err := w.Write([]byte("default\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatalf("unexpected Writer error on sync: %q", err.Error())
+	}
+
+	n, err := w.WriteTo("C87654321", []byte("override"))
+	if err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+	if n != len("override") {
+		t.Fatalf("unexpected WriteTo count %d (expected %d)", n, len("override"))
+	}
+
+	if _, err := w.Write([]byte("default again\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []Message{
+		{ChannelID: "C12345678", Text: "default"},
+		{ChannelID: "C87654321", Text: "override"},
+		{ChannelID: "C12345678", Text: "default again"},
+	}
+	if !reflect.DeepEqual(client.messages, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", client.messages, expected)
+	}
+
+	if _, err := w.WriteTo("C87654321", []byte("closed")); err != io.ErrClosedPipe {
+		t.Fatalf("unexpected WriteTo error after close: %v", err)
+	}
+}
+
+func TestWriterWriteToSerializeWrites(t *testing.T) {
+	var logs bytes.Buffer
+	c := initClient(
+		ClientOptionSerializeWrites(),
+		ClientOptionDryRun(),
+		ClientOptionLogger(log.New(&logs, "", 0)),
+	)
+	defer c.Close()
+
+	w := NewWriter(c, "C12345678", LineBatcher)
+	defer w.Close()
+
+	// While another Writer holds the lock for the target channel, WriteTo must
+	// wait rather than interleave its message with that Writer's output.
+	lock := c.channelLock("C87654321")
+	lock.Lock()
+
+	done := make(chan error)
+	go func() {
+		_, err := w.WriteTo("C87654321", []byte("override"))
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		lock.Unlock()
+		t.Fatal("WriteTo sent while another Writer held the channel's lock")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	lock.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected WriteTo error: %v", err)
+	}
+	if !strings.Contains(logs.String(), `"override"`) {
+		t.Fatalf("WriteTo did not send its message; got: %q", logs.String())
+	}
+}
+
+// recordingWriteClient records all messages sent to it without blocking.
+type recordingWriteClient struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (c *recordingWriteClient) SendMessage(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, m)
+	return nil
+}
+
+func (c *recordingWriteClient) texts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var texts []string
+	for _, m := range c.messages {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func TestWriterSync(t *testing.T) {
+	client := &recordingWriteClient{}
+
+	// With this interval, nothing would be sent before the test times out
+	// without an explicit Sync.
+	batcher := NewIntervalBatcher(LineBatcher, time.Hour, "\n")
+	w := NewWriter(client, "C12345678", batcher)
+
+	if _, err := w.Write([]byte("one\ntwo\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Sync(); err != nil {
+		t.Fatalf("unexpected Writer error on sync: %q", err.Error())
+	}
+
+	if texts := client.texts(); !reflect.DeepEqual(texts, []string{"one\ntwo"}) {
+		t.Fatalf("unexpected messages after sync: %#v", texts)
+	}
+
+	if _, err := w.Write([]byte("three\n")); err != nil {
+		t.Fatalf("unexpected Writer error after sync: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if texts := client.texts(); !reflect.DeepEqual(texts, []string{"one\ntwo", "three"}) {
+		t.Fatalf("unexpected messages after close: %#v", texts)
+	}
+
+	if err := w.Sync(); err != io.ErrClosedPipe {
+		t.Fatalf("unexpected Writer error on sync after close: %v", err)
+	}
+}
+
+func TestWriterDisableMrkdwn(t *testing.T) {
+	api := &testWebAPI{}
+	c := initClient()
+	c.api = api
+
+	w := NewWriter(c, "C12345678", LineBatcher, WriterOptionDisableMrkdwn(c))
+	if _, err := w.Write([]byte("some *literal* text\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if _, err := c.PostMessage(Message{ChannelID: "C12345678", Text: "some *bold* text"}); err != nil {
+		t.Fatalf("unexpected PostMessage error: %v", err)
+	}
+
+	if len(api.posts) != 2 {
+		t.Fatalf("unexpected post count %d (expected 2)", len(api.posts))
+	}
+
+	if mrkdwn := api.posts[0].values.Get("mrkdwn"); mrkdwn != "false" {
+		t.Errorf("unexpected mrkdwn %q from Writer (expected %q)", mrkdwn, "false")
+	}
+
+	if _, ok := api.posts[1].values["mrkdwn"]; ok {
+		t.Error("mrkdwn was set for a message that did not disable it")
+	}
+}
+
+func TestTemplateWriter(t *testing.T) {
+	client := &recordingWriteClient{}
+	tmpl := template.Must(template.New("alert").Parse("🚨 *{{.}}*"))
+	w := NewTemplateWriter(client, "C12345678", tmpl)
+
+	if _, err := w.Write([]byte("disk full\nhost down\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []string{"🚨 *disk full*", "🚨 *host down*"}
+	if texts := client.texts(); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, expected)
+	}
+}
+
+func TestTemplateWriterError(t *testing.T) {
+	client := &recordingWriteClient{}
+	tmpl := template.Must(template.New("alert").Parse("{{.Missing}}"))
+	w := NewTemplateWriter(client, "C12345678", tmpl)
+
+	if _, err := w.Write([]byte("disk full\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err == nil {
+		t.Fatal("template error was not returned on close")
+	}
+
+	if texts := client.texts(); len(texts) > 0 {
+		t.Fatalf("messages sent despite template error: %#v", texts)
+	}
+}
+
+func TestWriterBuffered(t *testing.T) {
+	client := &recordingWriteClient{}
+
+	// This Batcher does not read any input until released, as if it were stuck
+	// behind a slow connection to Slack.
+	release := make(chan struct{})
+	stalled := func(r io.Reader) (<-chan string, <-chan error) {
+		<-release
+		return LineBatcher(r)
+	}
+
+	w := NewWriter(client, "C12345678", stalled, WriterOptionBuffered())
+
+	input := bytes.Repeat([]byte("line\n"), 100000)
+	written := make(chan int, 1)
+	go func() {
+		n, err := w.Write(input)
+		if err != nil {
+			t.Errorf("unexpected Writer error: %q", err.Error())
+		}
+		written <- n
+	}()
+
+	select {
+	case n := <-written:
+		if n != len(input) {
+			t.Fatalf("unexpected write count %d (expected %d)", n, len(input))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("buffered Write blocked on a stalled Batcher")
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if texts := client.texts(); len(texts) != 100000 {
+		t.Fatalf("unexpected message count %d (expected 100000)", len(texts))
+	}
+}
+
+func TestWriterMarkdown(t *testing.T) {
+	client := &recordingWriteClient{}
+	w := NewWriter(client, "C12345678", LineBatcher, WriterOptionMarkdown())
+
+	if _, err := w.Write([]byte("**done** in [build 42](https://example.com/42)\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []string{"*done* in <https://example.com/42|build 42>"}
+	if texts := client.texts(); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, expected)
+	}
+}
+
+func TestWriterDropsEmptyBatches(t *testing.T) {
+	client := &recordingWriteClient{}
+	w := NewWriter(client, "C12345678", LineBatcher)
+
+	if _, err := w.Write([]byte("a\n\n \t\nb\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if texts := client.texts(); !reflect.DeepEqual(texts, []string{"a", "b"}) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, []string{"a", "b"})
+	}
+}
+
+func TestWriterSanitizeUTF8(t *testing.T) {
+	testCases := []struct {
+		description string
+		replacement string
+		expected    []string
+	}{
+		{"replace", "\uFFFD", []string{"ok \uFFFD done", "\uFFFDx", "\uFFFD"}},
+		{"drop", "", []string{"ok  done", "x"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := &recordingWriteClient{}
+			w := NewWriter(client, "C12345678", LineBatcher, WriterOptionSanitizeUTF8(tc.replacement))
+
+			if _, err := w.Write([]byte("ok \xff\xfe done\n\xc3x\n\x80\n")); err != nil {
+				t.Fatalf("unexpected Writer error: %q", err.Error())
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected Writer error on close: %q", err.Error())
+			}
+
+			texts := client.texts()
+			for _, text := range texts {
+				if !utf8.ValidString(text) {
+					t.Errorf("invalid UTF-8 in message %q", text)
+				}
+			}
+
+			if !reflect.DeepEqual(texts, tc.expected) {
+				t.Fatalf("unexpected messages %#v (expected %#v)", texts, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRoundRobinWriter(t *testing.T) {
+	client := &recordingWriteClient{}
+	channelIDs := []string{"C11111111", "C22222222", "C33333333"}
+	w := NewRoundRobinWriter(client, channelIDs, LineBatcher)
+
+	if _, err := w.Write([]byte("1\n2\n3\n4\n5\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []Message{
+		{ChannelID: "C11111111", Text: "1"},
+		{ChannelID: "C22222222", Text: "2"},
+		{ChannelID: "C33333333", Text: "3"},
+		{ChannelID: "C11111111", Text: "4"},
+		{ChannelID: "C22222222", Text: "5"},
+	}
+	if !reflect.DeepEqual(client.messages, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", client.messages, expected)
+	}
+}
+
+func TestNewRoundRobinWriterRequiresChannelIDs(t *testing.T) {
+	for _, channelIDs := range [][]string{nil, {"C12345678", ""}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRoundRobinWriter did not panic with channels %q", channelIDs)
+				}
+			}()
+
+			NewRoundRobinWriter(&recordingWriteClient{}, channelIDs, nil)
+		}()
+	}
+}
+
+type panickingWriteClient struct {
+	recordingWriteClient
+}
+
+func (c *panickingWriteClient) SendMessage(m Message) error {
+	if m.Text == "bad" {
+		panic("bad message")
+	}
+	return c.recordingWriteClient.SendMessage(m)
+}
+
+func TestWriterRecover(t *testing.T) {
+	client := &panickingWriteClient{}
+
+	var recovered []interface{}
+	w := NewWriter(client, "C12345678", LineBatcher, WriterOptionRecover(func(v interface{}) {
+		recovered = append(recovered, v)
+	}))
+
+	if _, err := w.Write([]byte("good\nbad\nalso good\n")); err != nil {
+		t.Fatalf("unexpected Writer error: %q", err.Error())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	expected := []string{"good", "also good"}
+	if texts := client.texts(); !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("unexpected messages %#v (expected %#v)", texts, expected)
+	}
+
+	if !reflect.DeepEqual(recovered, []interface{}{"bad message"}) {
+		t.Fatalf("unexpected recovered panics %v (expected [bad message])", recovered)
+	}
+}
+
+func TestWriterTap(t *testing.T) {
+	client := &recordingWriteClient{}
+
+	var tap bytes.Buffer
+	w := NewWriter(client, "C12345678", LineBatcher, WriterOptionTap(&tap))
+
+	input := [][]byte{[]byte("first\n"), []byte("  \n"), []byte("second\r\n")}
+	for _, p := range input {
+		if _, err := w.Write(p); err != nil {
+			t.Fatalf("unexpected Writer error: %q", err.Error())
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Writer error on close: %q", err.Error())
+	}
+
+	if expected := bytes.Join(input, nil); !bytes.Equal(tap.Bytes(), expected) {
+		t.Fatalf("unexpected tap contents %q (expected %q)", tap.Bytes(), expected)
+	}
+}